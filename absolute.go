@@ -0,0 +1,77 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GETAbsolute performs a GET petition to a fully qualified URL, like a pre
+// signed download link, ignoring the client base path, port, version and
+// service. The rest of the client configuration, like its headers, applies,
+// but the api key and the sensitive headers, like Authorization, are only
+// sent to the origin of the client, never to third party hosts.
+func (client *Client) GETAbsolute(rawURL string, query map[string][]string) (*http.Response, error) {
+	return client.executeAbsoluteCall(http.MethodGet, rawURL, nil, query)
+}
+
+// POSTAbsolute performs a POST petition to a fully qualified URL. See
+// GETAbsolute.
+func (client *Client) POSTAbsolute(rawURL string, body interface{}, query map[string][]string) (*http.Response, error) {
+	return client.executeAbsoluteCall(http.MethodPost, rawURL, body, query)
+}
+
+// PUTAbsolute performs a PUT petition to a fully qualified URL. See
+// GETAbsolute.
+func (client *Client) PUTAbsolute(rawURL string, body interface{}, query map[string][]string) (*http.Response, error) {
+	return client.executeAbsoluteCall(http.MethodPut, rawURL, body, query)
+}
+
+// DELETEAbsolute performs a DELETE petition to a fully qualified URL. See
+// GETAbsolute.
+func (client *Client) DELETEAbsolute(rawURL string, body interface{}, query map[string][]string) (*http.Response, error) {
+	return client.executeAbsoluteCall(http.MethodDelete, rawURL, body, query)
+}
+
+func (client *Client) executeAbsoluteCall(method, rawURL string, body interface{}, query map[string][]string) (*http.Response, error) {
+	endpoint, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if !endpoint.IsAbs() || endpoint.Host == "" {
+		return nil, fmt.Errorf("%q is not an absolute URL", rawURL)
+	}
+
+	if !client.isSameOrigin(endpoint) {
+		client = client.crossOrigin()
+	}
+
+	return client.execute(method, endpoint, rawURL, body, query, client.snapshotHeaders())
+}
+
+// isSameOrigin reports whether the URL has the scheme and host of the client
+// URI.
+func (client *Client) isSameOrigin(target *url.URL) bool {
+	origin, err := url.Parse(client.getURI())
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(origin.Scheme, target.Scheme) && strings.EqualFold(origin.Host, target.Host)
+}
+
+// crossOrigin returns a copy of the client without its api key and sensitive
+// headers, for calls to other origins.
+func (client *Client) crossOrigin() *Client {
+	clone := client.clone()
+	clone.apiKey = ""
+	clone.apiKeyFunc = nil
+	for header := range clone.headers {
+		if clone.sensitive[http.CanonicalHeaderKey(header)] {
+			delete(clone.headers, header)
+		}
+	}
+	return clone
+}
@@ -0,0 +1,116 @@
+package api_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func TestAbsoluteCalls(t *testing.T) {
+	Convey("Given a client to a service and a second server", t, func() {
+		var baseCalls int
+		base := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			baseCalls++
+		}))
+		defer base.Close()
+
+		var method, path, signature, body string
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method = r.Method
+			path = r.URL.Path
+			signature = r.URL.Query().Get("signature")
+			content, _ := ioutil.ReadAll(r.Body)
+			body = string(content)
+		}))
+		defer other.Close()
+
+		client := api.MakeNewClient().WithBasePath(base.URL).WithVersion(testVersion).ToService(testTargetService)
+
+		Convey("When we GET an absolute URL on the second server", func() {
+			resp, err := client.GETAbsolute(other.URL+"/downloads/1?signature=truman", nil)
+
+			Convey("Then the URL is used as is", func() {
+				checkResponseIsValid(resp, err)
+				So(method, ShouldEqual, http.MethodGet)
+				So(path, ShouldEqual, "/downloads/1")
+				So(signature, ShouldEqual, "truman")
+				So(baseCalls, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When we POST to an absolute URL with a query", func() {
+			resp, err := client.POSTAbsolute(other.URL+"/uploads", testPost, map[string][]string{"signature": {"capote"}})
+
+			Convey("Then the body and query are sent to the URL", func() {
+				checkResponseIsValid(resp, err)
+				So(method, ShouldEqual, http.MethodPost)
+				So(path, ShouldEqual, "/uploads")
+				So(signature, ShouldEqual, "capote")
+				So(body, ShouldContainSubstring, "Truman Capote")
+				So(baseCalls, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When we GET a relative URL", func() {
+			_, err := client.GETAbsolute(postsEndpoint, nil)
+
+			Convey("Then we obtain an error", func() {
+				So(err, ShouldNotBeNil)
+				So(baseCalls, ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestAbsoluteCallsCredentials(t *testing.T) {
+	Convey("Given a client with credentials and a third party server", t, func() {
+		record := func(received *http.Request) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				*received = *r
+			}
+		}
+		var own, third http.Request
+		base := httptest.NewServer(record(&own))
+		defer base.Close()
+		other := httptest.NewServer(record(&third))
+		defer other.Close()
+
+		client := api.MakeNewClient().WithBasePath(base.URL).WithAuthHeader(testAuthBearer).
+			WithSensitiveHeaders("X-Secret").WithAPIKey("api-key").WithTraceID("truman")
+		client.SetHeader("X-Secret", "secret")
+		query := map[string][]string{"signature": {"capote"}}
+
+		Convey("When we GET an absolute URL on the third party server", func() {
+			resp, err := client.GETAbsolute(other.URL+"/downloads/1", query)
+
+			Convey("Then neither the sensitive headers nor the api key are sent", func() {
+				checkResponseIsValid(resp, err)
+				So(third.Header.Get(authHeader), ShouldBeEmpty)
+				So(third.Header.Get("X-Secret"), ShouldBeEmpty)
+				So(third.URL.Query().Get("key"), ShouldBeEmpty)
+				So(third.URL.Query().Get("signature"), ShouldEqual, "capote")
+				So(third.Header.Get("X-Trace-Id"), ShouldEqual, "truman")
+			})
+
+			Convey("Then the client keeps its credentials", func() {
+				So(client.GetHeaders().Get(authHeader), ShouldEqual, testAuthBearer)
+			})
+		})
+
+		Convey("When we GET an absolute URL on the client origin", func() {
+			resp, err := client.GETAbsolute(base.URL+"/downloads/1", query)
+
+			Convey("Then the credentials are sent", func() {
+				checkResponseIsValid(resp, err)
+				So(own.Header.Get(authHeader), ShouldEqual, testAuthBearer)
+				So(own.Header.Get("X-Secret"), ShouldEqual, "secret")
+				So(own.URL.Query().Get("key"), ShouldEqual, "api-key")
+			})
+		})
+	})
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultBatchPath is the path the batch calls are posted to, unless set
+	// with WithBatchPath.
+	DefaultBatchPath = "/batch"
+
+	mixedContent     = "multipart/mixed"
+	httpContent      = "application/http"
+	contentIDHeader  = "Content-ID"
+	batchItemID      = "item-"
+	batchResponseID  = "response-" + batchItemID
+	contentIDBracket = "<>"
+)
+
+// BatchRequest models one of the calls sent in a batch. Header values are
+// added to the client headers for this call only.
+type BatchRequest struct {
+	Method string
+	Path   string
+	Body   interface{}
+	Query  map[string][]string
+	Header http.Header
+}
+
+// WithBatchPath sets the path the batch calls are posted to. By default, it
+// is DefaultBatchPath.
+func (client *Client) WithBatchPath(path string) *Client {
+	clone := client.clone()
+	clone.batchPath = path
+	return clone
+}
+
+// Batch sends several calls in a single multipart/mixed POST, as Google style
+// batch endpoints expect, and returns their responses in the same order as the
+// requests. Each call is encoded as an application/http part, built as the
+// client would build it on its own, and each part of the batch response is
+// parsed as the response of the call with the matching Content-ID, or of the
+// call in the same position when there is none. An error status of the batch
+// call itself is returned as a parsed error.
+func (client *Client) Batch(requests []BatchRequest) ([]*http.Response, error) {
+	subRequests := make([]*http.Request, len(requests))
+	for i, batchRequest := range requests {
+		subRequest, err := client.newBatchRequest(batchRequest)
+		if err != nil {
+			return nil, fmt.Errorf("Error: %v\nCan't build batch request %d", err, i)
+		}
+		subRequests[i] = subRequest
+	}
+
+	body, contentType, err := writeBatchBody(subRequests)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := client.snapshotHeaders().Clone()
+	headers.Set(contentTypeHeader, contentType)
+	resp, err := client.executeCallWithHeaders(http.MethodPost, client.getBatchPath(), body, nil, headers)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if !IsSuccess(resp) {
+		return nil, parseError(resp)
+	}
+
+	return readBatchResponses(resp, subRequests)
+}
+
+func (client *Client) getBatchPath() string {
+	if client.batchPath == "" {
+		return DefaultBatchPath
+	}
+
+	return client.batchPath
+}
+
+func (client *Client) newBatchRequest(batchRequest BatchRequest) (*http.Request, error) {
+	headers := client.snapshotHeaders().Clone()
+	for header, values := range batchRequest.Header {
+		for _, value := range values {
+			headers.Add(header, value)
+		}
+	}
+
+	endpoint, err := client.endpoint(client.expandPath(batchRequest.Path))
+	if err != nil {
+		return nil, err
+	}
+
+	return client.newRequest(batchRequest.Method, endpoint, batchRequest.Body, batchRequest.Query, headers)
+}
+
+func writeBatchBody(requests []*http.Request) (*bytes.Buffer, string, error) {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	for i, request := range requests {
+		partHeader := textproto.MIMEHeader{}
+		partHeader.Set(contentTypeHeader, httpContent)
+		partHeader.Set(contentIDHeader, "<"+batchItemID+strconv.Itoa(i+1)+">")
+
+		part, err := writer.CreatePart(partHeader)
+		if err != nil {
+			return nil, "", err
+		}
+
+		err = request.Write(part)
+		if err != nil {
+			return nil, "", fmt.Errorf("Error: %v\nCan't write batch request %d", err, i)
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return body, mime.FormatMediaType(mixedContent, map[string]string{"boundary": writer.Boundary()}), nil
+}
+
+func readBatchResponses(resp *http.Response, requests []*http.Request) ([]*http.Response, error) {
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get(contentTypeHeader))
+	if err != nil || mediaType != mixedContent {
+		return nil, fmt.Errorf("Batch response is not %s: %q", mixedContent, resp.Header.Get(contentTypeHeader))
+	}
+
+	responses := make([]*http.Response, len(requests))
+	reader := multipart.NewReader(resp.Body, params["boundary"])
+	for position := 0; ; position++ {
+		part, err := reader.NextPart()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("Error: %v\nCan't read batch response part %d", err, position)
+		}
+
+		i := batchResponseIndex(part.Header.Get(contentIDHeader), position)
+		if i < 0 || i >= len(requests) {
+			return nil, fmt.Errorf("Batch response part %d does not match any of the %d requests", position, len(requests))
+		}
+
+		responses[i], err = readBatchResponse(part, requests[i])
+		if err != nil {
+			return nil, fmt.Errorf("Error: %v\nCan't parse batch response part %d", err, position)
+		}
+	}
+
+	for i, response := range responses {
+		if response == nil {
+			return nil, fmt.Errorf("Batch response has no response for request %d", i)
+		}
+	}
+
+	return responses, nil
+}
+
+// batchResponseIndex returns the index of the request answered by a part with
+// the provided Content-ID, like <response-item-2>, or position if there is no
+// Content-ID.
+func batchResponseIndex(contentID string, position int) int {
+	contentID = strings.Trim(contentID, contentIDBracket)
+	if !strings.HasPrefix(contentID, batchResponseID) {
+		return position
+	}
+
+	n, err := strconv.Atoi(strings.TrimPrefix(contentID, batchResponseID))
+	if err != nil {
+		return position
+	}
+
+	return n - 1
+}
+
+// readBatchResponse parses the HTTP response of a part. Its body is read
+// before moving to the next part, so it stays readable.
+func readBatchResponse(part *multipart.Part, request *http.Request) (*http.Response, error) {
+	response, err := http.ReadResponse(bufio.NewReader(part), request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return response, nil
+}
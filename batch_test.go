@@ -0,0 +1,126 @@
+package api_test
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+// batchHandler answers each sub-request of a multipart/mixed batch with its
+// method, path and body, in reverse order, so responses must be matched by
+// Content-ID.
+func batchHandler(w http.ResponseWriter, r *http.Request) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var parts []string
+	var ids []string
+	reader := multipart.NewReader(r.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		request, err := http.ReadRequest(bufio.NewReader(part))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := ioutil.ReadAll(request.Body)
+
+		status := http.StatusOK
+		if request.Method == http.MethodDelete {
+			status = http.StatusNotFound
+		}
+		response := fmt.Sprintf("HTTP/1.1 %d %s\r\nContent-Type: application/json\r\n\r\n{\"method\":%q,\"path\":%q,\"auth\":%q,\"body\":%q}",
+			status, http.StatusText(status), request.Method, request.URL.Path, request.Header.Get("Authorization"), body)
+		parts = append(parts, response)
+		ids = append(ids, strings.Replace(part.Header.Get("Content-ID"), "<", "<response-", 1))
+	}
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	for i := len(parts) - 1; i >= 0; i-- {
+		part, _ := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {"application/http"},
+			"Content-Id":   {ids[i]},
+		})
+		part.Write([]byte(parts[i]))
+	}
+	writer.Close()
+
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+writer.Boundary())
+	w.Write(body.Bytes())
+}
+
+type batchEcho struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Auth   string `json:"auth"`
+	Body   string `json:"body"`
+}
+
+func TestBatch(t *testing.T) {
+	Convey("Given a batch endpoint", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(batchHandler))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithAuthHeader(testAuthBearer)
+
+		Convey("When we send a batch of calls", func() {
+			responses, err := client.Batch([]api.BatchRequest{
+				{Method: http.MethodGet, Path: postsEndpoint + "/1"},
+				{Method: http.MethodPost, Path: postsEndpoint, Body: map[string]string{"title": "A sangre fría"}},
+				{Method: http.MethodDelete, Path: postsEndpoint + "/2", Header: http.Header{"X-Reason": {"duplicated"}}},
+			})
+
+			Convey("Then we obtain a response per call, in order", func() {
+				So(err, ShouldBeNil)
+				So(responses, ShouldHaveLength, 3)
+
+				get := new(batchEcho)
+				So(api.ParseResponseTo(responses[0], get), ShouldBeNil)
+				So(get.Method, ShouldEqual, http.MethodGet)
+				So(get.Path, ShouldEqual, postsEndpoint+"/1")
+				So(get.Auth, ShouldEqual, testAuthBearer)
+
+				post := new(batchEcho)
+				So(api.ParseResponseTo(responses[1], post), ShouldBeNil)
+				So(post.Method, ShouldEqual, http.MethodPost)
+				So(post.Body, ShouldEqual, `{"title":"A sangre fría"}`)
+
+				So(responses[2].StatusCode, ShouldEqual, http.StatusNotFound)
+				So(responses[2].Request.Method, ShouldEqual, http.MethodDelete)
+			})
+		})
+	})
+
+	Convey("Given a batch endpoint that fails", t, func() {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we send a batch", func() {
+			responses, err := client.Batch([]api.BatchRequest{{Method: http.MethodGet, Path: postsEndpoint}})
+
+			Convey("Then we obtain the parsed error", func() {
+				So(responses, ShouldBeNil)
+				So(api.IsErrorResponse(err), ShouldBeTrue)
+			})
+		})
+	})
+}
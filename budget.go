@@ -0,0 +1,64 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// WithBudget limits the resources the client can spend across its lifetime:
+// maxBytes of response bodies read and maxTime waiting for responses. Once
+// any of them is crossed, calls fail with ErrBudgetExceeded without reaching
+// the service, as do reads past the byte budget. Zero means unlimited.
+func (client *Client) WithBudget(maxBytes int64, maxTime time.Duration) *Client {
+	clone := client.clone()
+	clone.budget = &budget{maxBytes: maxBytes, maxTime: maxTime}
+	return clone
+}
+
+type budget struct {
+	maxBytes int64
+	maxTime  time.Duration
+	bytes    int64
+	elapsed  int64
+}
+
+func (b *budget) exceeded() bool {
+	if b == nil {
+		return false
+	}
+
+	return (b.maxBytes > 0 && atomic.LoadInt64(&b.bytes) > b.maxBytes) ||
+		(b.maxTime > 0 && time.Duration(atomic.LoadInt64(&b.elapsed)) > b.maxTime)
+}
+
+func (b *budget) spend(elapsed time.Duration) {
+	if b == nil {
+		return
+	}
+
+	atomic.AddInt64(&b.elapsed, int64(elapsed))
+}
+
+func (b *budget) track(response *http.Response) {
+	if b == nil || b.maxBytes <= 0 || response.Body == nil || response.Body == http.NoBody {
+		return
+	}
+
+	response.Body = &budgetBody{ReadCloser: response.Body, budget: b}
+}
+
+type budgetBody struct {
+	io.ReadCloser
+	budget *budget
+}
+
+func (body *budgetBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	if atomic.AddInt64(&body.budget.bytes, int64(n)) > body.budget.maxBytes {
+		return n, ErrBudgetExceeded
+	}
+
+	return n, err
+}
@@ -0,0 +1,64 @@
+package api_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func TestWithBudget(t *testing.T) {
+	Convey("Given a service answering 100 bytes bodies", t, func() {
+		calls := new(int32)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(calls, 1)
+			if r.URL.Path == "/slow" {
+				time.Sleep(50 * time.Millisecond)
+			}
+			w.Write([]byte(strings.Repeat("a", 100)))
+		}))
+		defer server.Close()
+
+		Convey("When a client with a 250 bytes budget makes several calls", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithBudget(250, 0)
+			var readErrors []error
+			for i := 0; i < 3; i++ {
+				resp, err := client.GET(postsEndpoint, nil)
+				So(err, ShouldBeNil)
+				_, err = ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				readErrors = append(readErrors, err)
+			}
+			_, err := client.GET(postsEndpoint, nil)
+
+			Convey("Then the read crossing the budget and the next calls fail", func() {
+				So(readErrors[0], ShouldBeNil)
+				So(readErrors[1], ShouldBeNil)
+				So(errors.Is(readErrors[2], api.ErrBudgetExceeded), ShouldBeTrue)
+				So(errors.Is(err, api.ErrBudgetExceeded), ShouldBeTrue)
+				So(atomic.LoadInt32(calls), ShouldEqual, 3)
+			})
+		})
+
+		Convey("When a client with a time budget makes a slow call", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithBudget(0, 10*time.Millisecond)
+			resp, err := client.GET("/slow", nil)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+			_, err = client.GET(postsEndpoint, nil)
+
+			Convey("Then the next call fails", func() {
+				So(errors.Is(err, api.ErrBudgetExceeded), ShouldBeTrue)
+				So(atomic.LoadInt32(calls), ShouldEqual, 1)
+			})
+		})
+	})
+}
@@ -0,0 +1,561 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+)
+
+var errMalformedCacheEntry = errors.New("malformed cache entry")
+
+var defaultCacheMethods = []string{http.MethodGet, http.MethodHead}
+
+const (
+	varyHeader = "Vary"
+	varyAny    = "*"
+)
+
+// defaultCachedHeaders are the response headers stored in the cache, unless
+// set with WithCachedHeaders.
+var defaultCachedHeaders = []string{contentTypeHeader, etagHeader}
+
+// cachedResponse is the snapshot of a response stored in the cache.
+type cachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       []byte      `json:"body,omitempty"`
+	Expires    time.Time   `json:"expires"`
+}
+
+// cachedVariants are the cached responses of a call, one per combination of
+// the values of the request headers listed by the Vary header of the service,
+// like Accept-Language, so each representation of the resource is served only
+// to the requests that select it.
+type cachedVariants struct {
+	Vary     []string                   `json:"vary,omitempty"`
+	Variants map[string]*cachedResponse `json:"variants"`
+}
+
+// variantKey identifies the variant selected by the request headers.
+func (variants *cachedVariants) variantKey(headers http.Header) string {
+	digest := sha256.New()
+	for _, header := range variants.Vary {
+		writeKeyComponent(digest, headers.Values(header))
+	}
+
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+// add stores the response as the variant selected by the request headers. A
+// response that varies on other headers than the stored ones replaces them.
+// Expired variants are dropped.
+func (variants *cachedVariants) add(vary []string, headers http.Header, response *cachedResponse) {
+	if variants.Variants == nil || !equalHeaders(variants.Vary, vary) {
+		variants.Vary = vary
+		variants.Variants = map[string]*cachedResponse{}
+	}
+
+	now := time.Now()
+	for key, variant := range variants.Variants {
+		if variant.expired(now) {
+			delete(variants.Variants, key)
+		}
+	}
+	variants.Variants[variants.variantKey(headers)] = response
+}
+
+// ttl returns how long the variants must be kept, which is until the last one
+// expires, or zero if any of them never does.
+func (variants *cachedVariants) ttl() time.Duration {
+	var last time.Time
+	for _, variant := range variants.Variants {
+		if variant.Expires.IsZero() {
+			return 0
+		}
+		if variant.Expires.After(last) {
+			last = variant.Expires
+		}
+	}
+
+	return time.Until(last)
+}
+
+func (cached *cachedResponse) expired(now time.Time) bool {
+	return !cached.Expires.IsZero() && !now.Before(cached.Expires)
+}
+
+// varyHeaders returns the canonical names, in order, of the request headers
+// listed by the Vary header of the response. It reports false if the response
+// varies on anything, so it can't be cached.
+func varyHeaders(response *http.Response) ([]string, bool) {
+	var vary []string
+	for _, value := range response.Header.Values(varyHeader) {
+		for _, header := range strings.Split(value, ",") {
+			header = strings.TrimSpace(header)
+			if header == varyAny {
+				return nil, false
+			}
+			if header != "" {
+				vary = append(vary, http.CanonicalHeaderKey(header))
+			}
+		}
+	}
+	sort.Strings(vary)
+
+	return vary, true
+}
+
+func equalHeaders(first, second []string) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newCachedResponse(response *http.Response, body []byte, headers []string) *cachedResponse {
+	cachedHeader := http.Header{}
+	for _, header := range headers {
+		key := http.CanonicalHeaderKey(header)
+		if values, ok := response.Header[key]; ok {
+			cachedHeader[key] = values
+		}
+	}
+
+	return &cachedResponse{
+		StatusCode: response.StatusCode,
+		Header:     cachedHeader,
+		Body:       body,
+	}
+}
+
+func (cached *cachedResponse) toResponse() *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", cached.StatusCode, http.StatusText(cached.StatusCode)),
+		StatusCode:    cached.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cached.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+	}
+}
+
+func (client *Client) callCached(
+	method, path string,
+	body interface{},
+	query map[string][]string,
+	headers http.Header,
+) (*http.Response, bool) {
+
+	if !client.shouldCacheCall(method, body) {
+		return nil, false
+	}
+
+	key := client.cacheKey(method, path, body, query, headers)
+	response, err := client.getResponseFromCache(key, headers)
+	if err != nil {
+		if err != badger.ErrKeyNotFound {
+			client.logger.Warnf("Can't read cached response for [%s] %s: %v\n", method, path, err)
+		}
+		return nil, false
+	}
+
+	return response, true
+}
+
+// WithCachedHeaders sets the response headers stored in the cache with the
+// responses, so entries are not bloated with headers nobody reads. By default,
+// only Content-Type and ETag are stored.
+func (client *Client) WithCachedHeaders(headers ...string) *Client {
+	clone := client.clone()
+	clone.cacheHdrs = append([]string{}, headers...)
+	return clone
+}
+
+func (client *Client) cachedHeaders() []string {
+	if client.cacheHdrs == nil {
+		return defaultCachedHeaders
+	}
+
+	return client.cacheHdrs
+}
+
+func (client *Client) shouldCache(method string) bool {
+	return client.cacheDB != nil && client.cacheable[method]
+}
+
+// CacheReaderPolicy sets how calls whose body is an io.Reader are cached.
+type CacheReaderPolicy int
+
+const (
+	// BufferReaderBodies reads io.Reader bodies up front, so they are part of
+	// the cache key, and sends them as read.
+	BufferReaderBodies CacheReaderPolicy = iota
+	// SkipReaderBodies bypasses the cache for calls with an io.Reader body.
+	SkipReaderBodies
+)
+
+// bufferedBody is an io.Reader body read up front. It is sent as read, and its
+// content, instead of the reader state, is part of the cache key.
+type bufferedBody struct {
+	*bytes.Reader
+	content []byte
+}
+
+func (body *bufferedBody) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct{ Reader []byte }{body.content})
+}
+
+// bufferCachedBody reads the io.Reader body of a cached call, unless the
+// policy skips them.
+func (client *Client) bufferCachedBody(method string, body interface{}) (interface{}, error) {
+	reader, isReader := body.(io.Reader)
+	if !isReader || client.bodyPolicy != BufferReaderBodies || !client.shouldCache(method) {
+		return body, nil
+	}
+
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("Error: %v\nCan't read the body to cache the call", err)
+	}
+
+	return &bufferedBody{Reader: bytes.NewReader(content), content: content}, nil
+}
+
+// shouldCacheCall checks if the call can be cached, which is not the case for
+// calls with an unbuffered io.Reader body, as it can't be part of the key.
+func (client *Client) shouldCacheCall(method string, body interface{}) bool {
+	if _, isBuffered := body.(*bufferedBody); isBuffered {
+		return client.shouldCache(method)
+	}
+
+	_, isReader := body.(io.Reader)
+	return !isReader && client.shouldCache(method)
+}
+
+// cacheTTLFor returns how long the response can be cached, if at all. Error
+// responses are not cached, except 404s when the negative cache is enabled.
+func (client *Client) cacheTTLFor(response *http.Response) (time.Duration, bool) {
+	if response.StatusCode == http.StatusNotFound && client.missingTTL > 0 {
+		return client.missingTTL, true
+	}
+
+	return client.cacheTTL, IsSuccess(response)
+}
+
+// cacheKey returns the cache key of a call of the client. The path is resolved
+// against the client URI, so clones targeting other services, versions or
+// hosts, which share the cache, don't answer each other calls. The api key and
+// the sensitive headers, like Authorization, of the call are part of the key
+// too, so clients with different credentials never get each other responses.
+// The headers the service varies on select a variant under the key instead.
+func (client *Client) cacheKey(
+	method, path string,
+	body interface{},
+	query map[string][]string,
+	headers http.Header,
+) []byte {
+
+	target := path
+	if parsed, err := url.Parse(path); err != nil || !parsed.IsAbs() {
+		target = client.getURI() + strings.TrimLeft(path, uriSeparator)
+	}
+
+	if key := client.getAPIKey(client.ctx); key != "" {
+		query = MergeQueries(query, map[string][]string{keyQuery: {key}})
+	}
+
+	keyHeaders := http.Header{}
+	for _, header := range client.sensitiveHeaders() {
+		if values, ok := headers[header]; ok {
+			keyHeaders[header] = values
+		}
+	}
+
+	return getCacheKey(method, target, body, query, keyHeaders)
+}
+
+// getCacheKey hashes the call components, including all the provided headers,
+// into a fixed-size key. Each component is length-prefixed so different splits
+// of the same bytes can't collide.
+func getCacheKey(method, path string, body interface{}, query map[string][]string, headers http.Header) []byte {
+	digest := sha256.New()
+
+	writeKeyComponent(digest, method)
+	writeKeyComponent(digest, path)
+	writeKeyComponent(digest, body)
+	writeKeyComponent(digest, sortQuery(query))
+	writeKeyComponent(digest, sortQuery(headers))
+
+	return digest.Sum(nil)
+}
+
+func writeKeyComponent(digest hash.Hash, value interface{}) {
+	component, _ := json.Marshal(value)
+	length := make([]byte, 8)
+	binary.BigEndian.PutUint64(length, uint64(len(component)))
+
+	digest.Write(length)
+	digest.Write(component)
+}
+
+type queryParam struct {
+	Key    string
+	Values []string
+}
+
+func sortQuery(query map[string][]string) []queryParam {
+	params := make([]queryParam, 0, len(query))
+	for key, values := range query {
+		params = append(params, queryParam{Key: key, Values: values})
+	}
+
+	sort.Slice(params, func(i, j int) bool {
+		return params[i].Key < params[j].Key
+	})
+
+	return params
+}
+
+func (client *Client) getResponseFromCache(key []byte, headers http.Header) (*http.Response, error) {
+	variants := new(cachedVariants)
+	err := client.cacheDB.View(func(txn *badger.Txn) error {
+		return readVariants(txn, key, variants)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cached, ok := variants.Variants[variants.variantKey(headers)]
+	if !ok || cached.expired(time.Now()) {
+		return nil, badger.ErrKeyNotFound
+	}
+	if cached.StatusCode == 0 {
+		return nil, errMalformedCacheEntry
+	}
+
+	return cached.toResponse(), nil
+}
+
+func readVariants(txn *badger.Txn, key []byte, variants *cachedVariants) error {
+	item, err := txn.Get(key)
+	if err != nil {
+		return err
+	}
+
+	return item.Value(func(val []byte) error {
+		return json.Unmarshal(val, variants)
+	})
+}
+
+// cache stores a snapshot of the response. As the body must be read to be
+// stored, it is replaced by an in-memory copy so the caller can still read it.
+func (client *Client) cache(
+	method, path string,
+	body interface{},
+	query map[string][]string,
+	headers http.Header,
+	response *http.Response,
+) error {
+
+	ttl, cacheable := client.cacheTTLFor(response)
+	if !client.shouldCacheCall(method, body) || !cacheable {
+		return nil
+	}
+
+	vary, cacheable := varyHeaders(response)
+	if !cacheable {
+		return nil
+	}
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return err
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+
+	key := client.cacheKey(method, path, body, query, headers)
+	cached := newCachedResponse(response, responseBody, client.cachedHeaders())
+	if ttl > 0 {
+		cached.Expires = time.Now().Add(ttl)
+	}
+
+	cacheDB := client.cacheDB
+	write := func() {
+		err := cacheDB.Update(func(txn *badger.Txn) error {
+			return storeVariant(txn, key, vary, headers, cached)
+		})
+		if err != nil {
+			client.logger.Warnf("Can't cache response for [%s] %s: %v\n", method, path, err)
+		}
+	}
+
+	if !client.writer.enqueue(write) {
+		write()
+	}
+
+	return nil
+}
+
+// DefaultCacheWriteQueue is the number of cache writes that can be pending
+// with WithConcurrentCacheWrites before calls wait for the writer.
+const DefaultCacheWriteQueue = 64
+
+// storeVariant adds the response to the cached variants of the call.
+func storeVariant(txn *badger.Txn, key []byte, vary []string, headers http.Header, cached *cachedResponse) error {
+	variants := new(cachedVariants)
+	err := readVariants(txn, key, variants)
+	if err != nil && err != badger.ErrKeyNotFound {
+		variants = new(cachedVariants)
+	}
+	variants.add(vary, headers, cached)
+
+	value, err := json.Marshal(variants)
+	if err != nil {
+		return err
+	}
+
+	entry := badger.NewEntry(key, value)
+	if ttl := variants.ttl(); ttl > 0 {
+		entry = entry.WithTTL(ttl)
+	}
+	return txn.SetEntry(entry)
+}
+
+// WithConcurrentCacheWrites makes the cache writes happen in a background
+// worker, so calls return their response without waiting for them. Pending
+// writes are bounded by DefaultCacheWriteQueue, and Close waits for them to
+// complete.
+func (client *Client) WithConcurrentCacheWrites() *Client {
+	if client.writer != nil {
+		return client
+	}
+
+	clone := client.clone()
+	clone.writer = newCacheWriter(DefaultCacheWriteQueue)
+	return clone
+}
+
+// cacheWriter runs the cache writes, in order, in a single background worker.
+type cacheWriter struct {
+	mu     sync.Mutex
+	closed bool
+	writes chan func()
+	done   chan struct{}
+}
+
+func newCacheWriter(queue int) *cacheWriter {
+	writer := &cacheWriter{
+		writes: make(chan func(), queue),
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(writer.done)
+		for write := range writer.writes {
+			write()
+		}
+	}()
+
+	return writer
+}
+
+// enqueue schedules the write, and reports if it was. A nil or closed writer
+// does not schedule writes.
+func (writer *cacheWriter) enqueue(write func()) bool {
+	if writer == nil {
+		return false
+	}
+
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	if writer.closed {
+		return false
+	}
+
+	writer.writes <- write
+	return true
+}
+
+// close waits for the pending writes to complete.
+func (writer *cacheWriter) close() {
+	if writer == nil {
+		return
+	}
+
+	writer.mu.Lock()
+	if !writer.closed {
+		writer.closed = true
+		close(writer.writes)
+	}
+	writer.mu.Unlock()
+
+	<-writer.done
+}
+
+// InvalidateCache removes the cached responses of a call, for every variant of
+// the resource, so the next identical call reaches the service. The path
+// placeholders are expanded with the path params of the client, as in calls. Use it after a mutation to drop the stale entry of
+// the corresponding GET.
+func (client *Client) InvalidateCache(method, path string, body interface{}, query map[string][]string) error {
+	if client.cacheDB == nil {
+		return NewCacheNotEnabledError()
+	}
+
+	key := client.cacheKey(method, client.expandPath(path), body, query, client.snapshotHeaders())
+	return client.cacheDB.Update(func(txn *badger.Txn) error {
+		return txn.Delete(key)
+	})
+}
+
+// ClearCache removes all the cached responses.
+func (client *Client) ClearCache() error {
+	if client.cacheDB == nil {
+		return NewCacheNotEnabledError()
+	}
+
+	return client.cacheDB.DropAll()
+}
+
+// CacheNotEnabledError is used when a cache operation is requested on a client
+// without cache.
+type CacheNotEnabledError struct{}
+
+func (e *CacheNotEnabledError) Error() string {
+	return fmt.Sprintf("Cache is not enabled for this client")
+}
+
+// NewCacheNotEnabledError returns a new CacheNotEnabledError error.
+func NewCacheNotEnabledError() error {
+	return &CacheNotEnabledError{}
+}
+
+// IsCacheNotEnabledError checks if the error is a CacheNotEnabledError error.
+func IsCacheNotEnabledError(err error) bool {
+	_, ok := err.(*CacheNotEnabledError)
+	return ok
+}
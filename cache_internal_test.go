@@ -0,0 +1,190 @@
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCallCachedMalformedEntry(t *testing.T) {
+	Convey("Given a client with cache enabled", t, func() {
+		client := MakeNewClient().WithCache()
+
+		Convey("When the cache holds a malformed entry for a call", func() {
+			key := client.cacheKey(http.MethodGet, "/posts", nil, nil, nil)
+			err := client.cacheDB.Update(func(txn *badger.Txn) error {
+				return txn.Set(key, []byte("not a cached response"))
+			})
+			So(err, ShouldBeNil)
+
+			response, isCached := client.callCached(http.MethodGet, "/posts", nil, nil, nil)
+
+			Convey("Then the call is reported as a miss", func() {
+				So(isCached, ShouldBeFalse)
+				So(response, ShouldBeNil)
+			})
+		})
+
+		Convey("When the cache holds an empty entry for a call", func() {
+			key := client.cacheKey(http.MethodGet, "/posts", nil, nil, nil)
+			err := client.cacheDB.Update(func(txn *badger.Txn) error {
+				return txn.Set(key, []byte("{}"))
+			})
+			So(err, ShouldBeNil)
+
+			response, isCached := client.callCached(http.MethodGet, "/posts", nil, nil, nil)
+
+			Convey("Then the call is reported as a miss", func() {
+				So(isCached, ShouldBeFalse)
+				So(response, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestGetCacheKey(t *testing.T) {
+	Convey("Given two equivalent queries built in different order", t, func() {
+		first := map[string][]string{}
+		first["author"] = []string{"Truman Capote"}
+		first["title"] = []string{"Desayuno con diamantes"}
+		first["$limit"] = []string{"10"}
+		second := map[string][]string{}
+		second["$limit"] = []string{"10"}
+		second["title"] = []string{"Desayuno con diamantes"}
+		second["author"] = []string{"Truman Capote"}
+
+		Convey("When we compute their cache keys", func() {
+			firstKey := getCacheKey(http.MethodGet, "/posts", nil, first, nil)
+			secondKey := getCacheKey(http.MethodGet, "/posts", nil, second, nil)
+
+			Convey("Then both keys are the same", func() {
+				So(firstKey, ShouldResemble, secondKey)
+			})
+		})
+	})
+
+	Convey("Given two calls whose components concatenate to the same bytes", t, func() {
+		Convey("When we compute their cache keys", func() {
+			firstKey := getCacheKey("GET", "/ab", nil, nil, nil)
+			secondKey := getCacheKey("GE", "T/ab", nil, nil, nil)
+
+			Convey("Then the keys are different and fixed-size", func() {
+				So(firstKey, ShouldNotResemble, secondKey)
+				So(len(firstKey), ShouldEqual, len(secondKey))
+			})
+		})
+	})
+}
+
+func TestWithPersistentCache(t *testing.T) {
+	Convey("Given a directory for the cache", t, func() {
+		dir, err := ioutil.TempDir("", "blackbeard-cache")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+		}))
+		defer server.Close()
+
+		Convey("When a call is cached and the client is restarted", func() {
+			client, err := MakeNewClient().WithBasePath(server.URL).WithPersistentCache(dir)
+			So(err, ShouldBeNil)
+			_, err = client.GET("/posts", nil, nil)
+			So(err, ShouldBeNil)
+			So(client.Close(), ShouldBeNil)
+
+			restarted, err := MakeNewClient().WithBasePath(server.URL).WithPersistentCache(dir)
+			So(err, ShouldBeNil)
+			defer restarted.Close()
+			_, err = restarted.GET("/posts", nil, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then the cached response survives the restart", func() {
+				So(calls, ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given a path that is not a directory", t, func() {
+		file, err := ioutil.TempFile("", "blackbeard-cache")
+		So(err, ShouldBeNil)
+		file.Close()
+		defer os.Remove(file.Name())
+
+		Convey("When we enable the persistent cache on it", func() {
+			client, err := MakeNewClient().WithPersistentCache(file.Name())
+
+			Convey("Then we obtain the open error and caching stays disabled", func() {
+				So(err, ShouldNotBeNil)
+				So(client.cacheDB, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestWithConcurrentCacheWrites(t *testing.T) {
+	Convey("Given a client with concurrent cache writes whose writer is busy", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+		client := MakeNewClient().WithBasePath(server.URL).WithCache().WithConcurrentCacheWrites()
+		busy := make(chan struct{})
+		So(client.writer.enqueue(func() { <-busy }), ShouldBeTrue)
+
+		Convey("When we make a cacheable call", func() {
+			_, err := client.GET("/posts", nil, nil)
+			So(err, ShouldBeNil)
+			_, cachedBefore := client.callCached(http.MethodGet, "/posts", nil, nil, client.headers)
+			close(busy)
+
+			Convey("Then the response returns before the entry is written, and it eventually is", func() {
+				So(cachedBefore, ShouldBeFalse)
+				So(func() bool {
+					for i := 0; i < 100; i++ {
+						if _, isCached := client.callCached(http.MethodGet, "/posts", nil, nil, client.headers); isCached {
+							return true
+						}
+						time.Sleep(10 * time.Millisecond)
+					}
+					return false
+				}(), ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given a persistent cache with concurrent writes whose writer is busy", t, func() {
+		dir, err := ioutil.TempDir("", "blackbeard-cache")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+		owner, err := MakeNewClient().WithBasePath(server.URL).WithPersistentCache(dir)
+		So(err, ShouldBeNil)
+		client := owner.WithConcurrentCacheWrites()
+		busy := make(chan struct{})
+		So(client.writer.enqueue(func() { <-busy }), ShouldBeTrue)
+
+		Convey("When we make a cacheable call and close the client", func() {
+			_, err := client.GET("/posts", nil, nil)
+			So(err, ShouldBeNil)
+			time.AfterFunc(50*time.Millisecond, func() { close(busy) })
+			So(client.Close(), ShouldBeNil)
+			So(owner.Close(), ShouldBeNil)
+
+			restarted, err := MakeNewClient().WithBasePath(server.URL).WithPersistentCache(dir)
+			So(err, ShouldBeNil)
+			defer restarted.Close()
+			_, isCached := restarted.callCached(http.MethodGet, "/posts", nil, nil, restarted.headers)
+
+			Convey("Then Close waits for the pending write", func() {
+				So(isCached, ShouldBeTrue)
+			})
+		})
+	})
+}
@@ -0,0 +1,450 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+var testPost = map[string]interface{}{
+	"title":  "Desayuno con diamantes",
+	"author": "Truman Capote",
+}
+
+func TestCacheHit(t *testing.T) {
+	Convey("Given a client with cache enabled", t, func() {
+		server, calls := newCountingServer()
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithCache()
+
+		Convey("When we make the same GET call twice", func() {
+			first, err := client.GET(postsEndpoint, nil, nil)
+			So(err, ShouldBeNil)
+			firstBody, _ := ioutil.ReadAll(first.Body)
+			second, err := client.GET(postsEndpoint, nil, nil)
+			So(err, ShouldBeNil)
+			secondBody, _ := ioutil.ReadAll(second.Body)
+
+			Convey("Then the second response is served from the cache", func() {
+				So(atomic.LoadInt32(calls), ShouldEqual, 1)
+				So(second.StatusCode, ShouldEqual, first.StatusCode)
+				So(second.Header.Get("Content-Type"), ShouldEqual, first.Header.Get("Content-Type"))
+				So(string(secondBody), ShouldEqual, string(firstBody))
+			})
+		})
+	})
+}
+
+func TestCacheMiss(t *testing.T) {
+	Convey("Given a client with cache enabled", t, func() {
+		server, calls := newCountingServer()
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithCache()
+
+		Convey("When we make GET calls to different paths", func() {
+			_, err := client.GET(postsEndpoint, nil, nil)
+			So(err, ShouldBeNil)
+			_, err = client.GET(postsEndpoint+"/1", nil, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then every call reaches the network", func() {
+				So(atomic.LoadInt32(calls), ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func TestCacheTargets(t *testing.T) {
+	Convey("Given two clones of a cached client targeting different services", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"path":%q}`, r.URL.Path)
+		}))
+		defer server.Close()
+		base := api.MakeNewClient().WithBasePath(server.URL).WithCache()
+		first := base.ToService("truman")
+		second := base.ToService("capote")
+
+		Convey("When both make the same GET call", func() {
+			resp, err := first.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+			firstBody, _ := ioutil.ReadAll(resp.Body)
+			resp, err = second.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+			secondBody, _ := ioutil.ReadAll(resp.Body)
+
+			Convey("Then each one gets the response of its own service", func() {
+				So(string(firstBody), ShouldEqual, `{"path":"/truman/posts"}`)
+				So(string(secondBody), ShouldEqual, `{"path":"/capote/posts"}`)
+			})
+		})
+	})
+}
+
+type tenantKey struct{}
+
+func TestCacheTenants(t *testing.T) {
+	Convey("Given a cached client whose api key depends on the call tenant", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"key":%q}`, r.URL.Query().Get("key"))
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithCache().
+			WithAPIKeyFunc(func(ctx context.Context) string {
+				tenant, _ := ctx.Value(tenantKey{}).(string)
+				return tenant + "-key"
+			})
+		truman := client.WithContext(context.WithValue(context.Background(), tenantKey{}, "truman"))
+		capote := client.WithContext(context.WithValue(context.Background(), tenantKey{}, "capote"))
+
+		Convey("When both tenants make the same GET call", func() {
+			resp, err := truman.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+			trumanBody, _ := ioutil.ReadAll(resp.Body)
+			resp, err = capote.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+			capoteBody, _ := ioutil.ReadAll(resp.Body)
+
+			Convey("Then each tenant gets its own response", func() {
+				So(string(trumanBody), ShouldEqual, `{"key":"truman-key"}`)
+				So(string(capoteBody), ShouldEqual, `{"key":"capote-key"}`)
+			})
+		})
+	})
+}
+
+// newCountingServer returns a test server that answers every call with a JSON
+// body containing the number of calls received so far.
+func newCountingServer() (*httptest.Server, *int32) {
+	calls := new(int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := atomic.AddInt32(calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"call":%d}`, call)
+	}))
+
+	return server, calls
+}
+
+func TestCacheTTL(t *testing.T) {
+	Convey("Given a client with a short cache TTL", t, func() {
+		server, calls := newCountingServer()
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithCache().WithCacheTTL(time.Second)
+
+		Convey("When we repeat a GET call after the TTL elapses", func() {
+			_, err := client.GET(postsEndpoint, nil, nil)
+			So(err, ShouldBeNil)
+			_, err = client.GET(postsEndpoint, nil, nil)
+			So(err, ShouldBeNil)
+			So(atomic.LoadInt32(calls), ShouldEqual, 1)
+
+			time.Sleep(2 * time.Second)
+			_, err = client.GET(postsEndpoint, nil, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then the expired entry is a miss", func() {
+				So(atomic.LoadInt32(calls), ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func TestCacheVary(t *testing.T) {
+	Convey("Given a cached client and a service whose responses vary on some headers", t, func() {
+		calls := new(int32)
+		var vary string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(calls, 1)
+			if vary != "" {
+				w.Header().Set("Vary", vary)
+			}
+			w.Write([]byte(r.Header.Get("Accept")))
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithCache()
+		defer client.Close()
+		withAccept := func(accept string) *api.Client {
+			return client.WithHeaders(http.Header{"Accept": {accept}})
+		}
+
+		Convey("When the service varies on Accept and we call it with different ones", func() {
+			vary = "Accept-Encoding, Accept"
+			jsonBody := getBody(withAccept("application/json"))
+			xmlBody := getBody(withAccept("application/xml"))
+			cachedJSON := getBody(withAccept("application/json"))
+
+			Convey("Then each representation has its own cache entry", func() {
+				So(atomic.LoadInt32(calls), ShouldEqual, 2)
+				So(jsonBody, ShouldEqual, "application/json")
+				So(xmlBody, ShouldEqual, "application/xml")
+				So(cachedJSON, ShouldEqual, "application/json")
+			})
+		})
+
+		Convey("When the service doesn't vary on the locale and we call it with different ones", func() {
+			getBody(client.WithLocale("es"))
+			getBody(client.WithLocale("en"))
+
+			Convey("Then the locales share the cache entry", func() {
+				So(atomic.LoadInt32(calls), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the service varies on anything", func() {
+			vary = "*"
+			getBody(client)
+			getBody(client)
+
+			Convey("Then its responses are not cached", func() {
+				So(atomic.LoadInt32(calls), ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func TestWithNegativeCache(t *testing.T) {
+	Convey("Given a service answering 404", t, func() {
+		calls := new(int32)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(calls, 1)
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		Convey("When a client with negative cache repeats the call within the window", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithCache().WithNegativeCache(time.Minute)
+			_, err := client.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+			resp, err := client.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then the second 404 is served from cache", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusNotFound)
+				So(atomic.LoadInt32(calls), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When a client without negative cache repeats the call", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithCache()
+			_, err := client.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+			_, err = client.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then both calls reach the service", func() {
+				So(atomic.LoadInt32(calls), ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func TestCacheMethods(t *testing.T) {
+	Convey("Given a client with cache enabled", t, func() {
+		server, calls := newCountingServer()
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithCache()
+
+		Convey("When we make the same POST call twice", func() {
+			_, err := client.POST(postsEndpoint, testPost, nil)
+			So(err, ShouldBeNil)
+			_, err = client.POST(postsEndpoint, testPost, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then both calls reach the network", func() {
+				So(atomic.LoadInt32(calls), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When POST is explicitly marked as cacheable", func() {
+			client = client.WithCacheMethods(http.MethodPost)
+			_, err := client.POST(postsEndpoint, testPost, nil)
+			So(err, ShouldBeNil)
+			_, err = client.POST(postsEndpoint, testPost, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then the second call is served from the cache", func() {
+				So(atomic.LoadInt32(calls), ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func TestCacheReaderBodies(t *testing.T) {
+	Convey("Given a service that records the bodies it receives", t, func() {
+		var bodies []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			bodies = append(bodies, string(body))
+			w.Write(body)
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithCache().WithCacheMethods(http.MethodPost)
+
+		Convey("When we make cached calls whose bodies are readers", func() {
+			first, err := client.POST(postsEndpoint, strings.NewReader("Desayuno con diamantes"))
+			So(err, ShouldBeNil)
+			firstBody, _ := ioutil.ReadAll(first.Body)
+			second, err := client.POST(postsEndpoint, strings.NewReader("Desayuno con diamantes"))
+			So(err, ShouldBeNil)
+			secondBody, _ := ioutil.ReadAll(second.Body)
+			_, err = client.POST(postsEndpoint, strings.NewReader("A sangre fría"))
+			So(err, ShouldBeNil)
+
+			Convey("Then the bodies are sent intact and keyed by their content", func() {
+				So(bodies, ShouldResemble, []string{"Desayuno con diamantes", "A sangre fría"})
+				So(string(firstBody), ShouldEqual, "Desayuno con diamantes")
+				So(string(secondBody), ShouldEqual, "Desayuno con diamantes")
+			})
+		})
+
+		Convey("When reader bodies skip the cache", func() {
+			client = client.WithCacheReaderPolicy(api.SkipReaderBodies)
+			_, err := client.POST(postsEndpoint, strings.NewReader("Desayuno con diamantes"))
+			So(err, ShouldBeNil)
+			_, err = client.POST(postsEndpoint, strings.NewReader("Desayuno con diamantes"))
+			So(err, ShouldBeNil)
+
+			Convey("Then every call reaches the network with its body", func() {
+				So(bodies, ShouldResemble, []string{"Desayuno con diamantes", "Desayuno con diamantes"})
+			})
+		})
+	})
+}
+
+func TestWithCachedHeaders(t *testing.T) {
+	Convey("Given a service answering several headers", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"truman"`)
+			w.Header().Set("X-Request-Id", "capote")
+			w.Header().Set("Server-Timing", "db;dur=53")
+			w.Write([]byte(`{}`))
+		}))
+		defer server.Close()
+
+		Convey("When a response is served from the cache with the default headers", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithCache()
+			_, err := client.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+			cached, err := client.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then only Content-Type and ETag survive", func() {
+				So(cached.Header, ShouldResemble, http.Header{
+					"Content-Type": {"application/json"},
+					"Etag":         {`"truman"`},
+				})
+			})
+		})
+
+		Convey("When a response is served from the cache with whitelisted headers", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithCache().WithCachedHeaders("x-request-id")
+			_, err := client.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+			cached, err := client.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then only the whitelisted headers survive", func() {
+				So(cached.Header, ShouldResemble, http.Header{"X-Request-Id": {"capote"}})
+			})
+		})
+	})
+}
+
+func TestInvalidateCache(t *testing.T) {
+	Convey("Given a client with a cached GET call", t, func() {
+		server, calls := newCountingServer()
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithCache()
+		_, err := client.GET(postsEndpoint, nil, nil)
+		So(err, ShouldBeNil)
+
+		Convey("When we invalidate the call and repeat it", func() {
+			err := client.InvalidateCache(http.MethodGet, postsEndpoint, nil, nil)
+			So(err, ShouldBeNil)
+			_, err = client.GET(postsEndpoint, nil, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then the call reaches the network again", func() {
+				So(atomic.LoadInt32(calls), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When we clear the cache and repeat the call", func() {
+			err := client.ClearCache()
+			So(err, ShouldBeNil)
+			_, err = client.GET(postsEndpoint, nil, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then the call reaches the network again", func() {
+				So(atomic.LoadInt32(calls), ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("Given a client with path params and a cached GET call", t, func() {
+		server, calls := newCountingServer()
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithCache().
+			WithPathParams(map[string]string{"id": "1"})
+		_, err := client.GET(postsEndpoint+"/{id}", nil, nil)
+		So(err, ShouldBeNil)
+
+		Convey("When we invalidate the call by its placeholder path and repeat it", func() {
+			err := client.InvalidateCache(http.MethodGet, postsEndpoint+"/{id}", nil, nil)
+			So(err, ShouldBeNil)
+			_, err = client.GET(postsEndpoint+"/{id}", nil, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then the call reaches the network again", func() {
+				So(atomic.LoadInt32(calls), ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey(givenAClient+" without cache", t, func() {
+		client := api.MakeNewClient()
+
+		Convey("When we invalidate or clear the cache", func() {
+			invalidateErr := client.InvalidateCache(http.MethodGet, postsEndpoint, nil, nil)
+			clearErr := client.ClearCache()
+
+			Convey("Then we obtain a cache not enabled error", func() {
+				So(api.IsCacheNotEnabledError(invalidateErr), ShouldBeTrue)
+				So(api.IsCacheNotEnabledError(clearErr), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestWithCacheE(t *testing.T) {
+	Convey(givenAClient, t, func() {
+		server, calls := newCountingServer()
+		defer server.Close()
+
+		Convey("When we enable the cache handling its error", func() {
+			client, err := api.MakeNewClient().WithBasePath(server.URL).WithCacheE()
+			So(err, ShouldBeNil)
+			_, err = client.GET(postsEndpoint, nil, nil)
+			So(err, ShouldBeNil)
+			_, err = client.GET(postsEndpoint, nil, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then the cache is enabled", func() {
+				So(atomic.LoadInt32(calls), ShouldEqual, 1)
+			})
+		})
+	})
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// ChecksumAlgo is a digest algorithm for request body checksums.
+type ChecksumAlgo int
+
+const (
+	// ChecksumMD5 digests the body with MD5, as used by the Content-MD5 header.
+	ChecksumMD5 ChecksumAlgo = iota + 1
+	// ChecksumSHA256 digests the body with SHA-256.
+	ChecksumSHA256
+)
+
+func (algo ChecksumAlgo) new() hash.Hash {
+	switch algo {
+	case ChecksumSHA256:
+		return sha256.New()
+	default:
+		return md5.New()
+	}
+}
+
+type bodyChecksum struct {
+	header string
+	algo   ChecksumAlgo
+}
+
+// WithBodyChecksum sets the header to the base64 digest of the request body,
+// as it is sent, in every call with a body. Some storage services require it,
+// like Content-MD5 with ChecksumMD5.
+func (client *Client) WithBodyChecksum(header string, algo ChecksumAlgo) *Client {
+	clone := client.clone()
+	clone.checksum = &bodyChecksum{header: header, algo: algo}
+	return clone
+}
+
+func (client *Client) setBodyChecksum(request *http.Request) error {
+	if client.checksum == nil || request.Body == nil || request.Body == http.NoBody {
+		return nil
+	}
+
+	body, err := requestBody(request)
+	if err != nil {
+		return err
+	}
+
+	digest := client.checksum.algo.new()
+	_, err = io.Copy(digest, body)
+	body.Close()
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set(client.checksum.header, base64.StdEncoding.EncodeToString(digest.Sum(nil)))
+	return nil
+}
+
+// requestBody returns a copy of the request body. Bodies that can't be copied,
+// like streams, are read in memory so they can be sent afterwards.
+func requestBody(request *http.Request) (io.ReadCloser, error) {
+	if request.GetBody != nil {
+		return request.GetBody()
+	}
+
+	content, err := ioutil.ReadAll(request.Body)
+	request.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	request.Body = ioutil.NopCloser(bytes.NewReader(content))
+	request.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(content)), nil
+	}
+	request.ContentLength = int64(len(content))
+	return request.GetBody()
+}
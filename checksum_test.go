@@ -0,0 +1,65 @@
+package api_test
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func TestWithBodyChecksum(t *testing.T) {
+	Convey("Given a service that records the body and its checksum header", t, func() {
+		var body []byte
+		var checksum string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ = ioutil.ReadAll(r.Body)
+			checksum = r.Header.Get("X-Checksum")
+		}))
+		defer server.Close()
+		post := map[string]interface{}{"title": "Desayuno con diamantes"}
+		knownBody := `{"title":"Desayuno con diamantes"}`
+
+		Convey("When we POST a known body with an MD5 checksum", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithBodyChecksum("X-Checksum", api.ChecksumMD5)
+			_, err := client.POST(postsEndpoint, post)
+
+			Convey("Then the header is the base64 MD5 of the body", func() {
+				So(err, ShouldBeNil)
+				digest := md5.Sum([]byte(knownBody))
+				So(string(body), ShouldEqual, knownBody)
+				So(checksum, ShouldEqual, base64.StdEncoding.EncodeToString(digest[:]))
+				So(checksum, ShouldEqual, "2peW06u0ne2ZFPLa9FJoog==")
+			})
+		})
+
+		Convey("When we POST a stream with a SHA-256 checksum", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithBodyChecksum("X-Checksum", api.ChecksumSHA256)
+			_, err := client.POST(postsEndpoint, ioutil.NopCloser(strings.NewReader(knownBody)))
+
+			Convey("Then the header is the base64 SHA-256 of the body", func() {
+				So(err, ShouldBeNil)
+				digest := sha256.Sum256([]byte(knownBody))
+				So(string(body), ShouldEqual, knownBody)
+				So(checksum, ShouldEqual, base64.StdEncoding.EncodeToString(digest[:]))
+			})
+		})
+
+		Convey("When we GET without body", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithBodyChecksum("X-Checksum", api.ChecksumMD5)
+			_, err := client.GET(postsEndpoint, nil)
+
+			Convey("Then no checksum is sent", func() {
+				So(err, ShouldBeNil)
+				So(checksum, ShouldBeEmpty)
+			})
+		})
+	})
+}
@@ -3,18 +3,26 @@ package api
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/tls"
+	"encoding/xml"
 	"fmt"
 	"io"
+	"mime"
 	"mime/multipart"
+	"net"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dgraph-io/badger/v2"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -24,7 +32,16 @@ const (
 	keyQuery      = "key"
 )
 
+const (
+	schemeSeparator = "://"
+	httpScheme      = "http"
+	httpsScheme     = "https"
+)
+
 // Client get basic support to make requests to the admin service.
+// Builders, like WithAuthHeader, return a copy of the client with the change
+// applied and leave the receiver untouched, so a base client can be shared
+// and customized per call safely.
 type Client struct {
 	parentCtx  context.Context
 	ctx        context.Context
@@ -34,9 +51,46 @@ type Client struct {
 	service    string
 	httpClient *http.Client
 	headers    http.Header
+	headersMu  *sync.RWMutex
 	apiKey     string
+	apiKeyFunc func(ctx context.Context) string
 	cacheDB    *badger.DB
-	logger     Logger
+	ownsCache  bool
+	cacheTTL   time.Duration
+	missingTTL time.Duration
+	cacheable  map[string]bool
+	decoding   decodeOptions
+	sensitive  map[string]bool
+	middleware []Interceptor
+	onRequest  func(*http.Request)
+	onResponse func(*http.Response, time.Duration)
+	bodyLeaks  *leakDetector
+	tracer     trace.Tracer
+	metrics    MetricsObserver
+	validator  func(body interface{}) error
+	stall      time.Duration
+	pathParams map[string]string
+	resolver   func(service string) (string, int, error)
+	hedging    time.Duration
+	progress   ProgressFunc
+	gzipAbove  int64
+	maxBody    int64
+	budget     *budget
+	checksum   *bodyChecksum
+	ids        IDGenerator
+	meter      *otelInstruments
+	batchPath  string
+	bodyPolicy CacheReaderPolicy
+	fallback   func(method, path string) (*http.Response, error)
+	scheme     string
+	writer     *cacheWriter
+	cacheHdrs  []string
+	noKeyPaths []string
+
+	errorOnHTTPError bool
+	rawEncoding      bool
+	httpTrace        bool
+	logger           Logger
 }
 
 // MakeNewClient initializes and returns a new fresh service client.
@@ -45,58 +99,410 @@ func MakeNewClient() *Client {
 	client.httpClient = &http.Client{}
 	client.ctx = context.Background()
 	client.headers = http.Header{}
+	client.headersMu = new(sync.RWMutex)
+	client.headers.Set(userAgentHeader, defaultUserAgent)
 	client.logger = &noLogger{}
+	client.ids = UUIDGenerator{}
+
+	return client.WithSensitiveHeaders(authorizationHeader).WithCacheMethods(defaultCacheMethods...)
+}
 
-	return client
+// clone returns a copy of the client for a builder to apply its change to,
+// leaving the receiver untouched. The headers, the maps and slices builders
+// change and the HTTP client are copied. The cache, the budget and the rest
+// of the state built by the builders is shared with the receiver, but only the
+// client that enabled the cache owns it, and closes it.
+func (client *Client) clone() *Client {
+	client.headersMu.RLock()
+	clone := *client
+	clone.headers = client.headers.Clone()
+	client.headersMu.RUnlock()
+
+	clone.headersMu = new(sync.RWMutex)
+	clone.ownsCache = false
+	clone.cacheable = copyFlags(client.cacheable)
+	clone.sensitive = copyFlags(client.sensitive)
+	clone.middleware = append([]Interceptor(nil), client.middleware...)
+	clone.noKeyPaths = append([]string(nil), client.noKeyPaths...)
+
+	httpClient := *client.httpClient
+	clone.httpClient = &httpClient
+	return &clone
+}
+
+func copyFlags(flags map[string]bool) map[string]bool {
+	if flags == nil {
+		return nil
+	}
+
+	copied := make(map[string]bool, len(flags))
+	for key, value := range flags {
+		copied[key] = value
+	}
+	return copied
 }
 
 // WithLogger attach a logger to the client
 func (client *Client) WithLogger(logger Logger) *Client {
-	client.logger = logger
-	return client
+	clone := client.clone()
+	clone.logger = logger
+	return clone
 }
 
-// WithCache enables caching results for this client object.
+// WithCache enables caching results for this client object. If the cache can't
+// be initialized, the failure is logged and the client works without cache.
+// Use WithCacheE to handle the error.
 func (client *Client) WithCache() *Client {
+	clone, err := client.WithCacheE()
+	if err != nil {
+		client.logger.Errorf("Can't initialize the cache, caching is disabled: %v\n", err)
+	}
+	return clone
+}
+
+// WithCacheE enables caching results for this client object, returning the
+// error if the cache can't be initialized.
+func (client *Client) WithCacheE() (*Client, error) {
 	options := badger.DefaultOptions("").WithInMemory(true)
-	client.cacheDB, _ = badger.Open(options)
-	return client
+	cacheDB, err := badger.Open(options)
+	if err != nil {
+		return client, err
+	}
+
+	clone := client.clone()
+	clone.cacheDB = cacheDB
+	clone.ownsCache = true
+	return clone, nil
+}
+
+// WithPersistentCache enables caching results for this client object in an
+// on-disk database stored in dir, so cached responses survive restarts. The
+// database must be released by closing the returned client once it, and the
+// clients derived from it, are no longer used.
+func (client *Client) WithPersistentCache(dir string) (*Client, error) {
+	cacheDB, err := badger.Open(badger.DefaultOptions(dir))
+	if err != nil {
+		return client, err
+	}
+
+	clone := client.clone()
+	clone.cacheDB = cacheDB
+	clone.ownsCache = true
+	return clone, nil
+}
+
+// WithCacheTTL sets how long a cached response is served before it expires.
+// A zero duration, the default, means cached responses never expire.
+func (client *Client) WithCacheTTL(duration time.Duration) *Client {
+	clone := client.clone()
+	clone.cacheTTL = duration
+	return clone
+}
+
+// WithNegativeCache caches 404 responses for the provided duration, so calls
+// to missing resources don't reach the service again until it expires. Without
+// it, only 2XX and 3XX responses are cached.
+func (client *Client) WithNegativeCache(duration time.Duration) *Client {
+	clone := client.clone()
+	clone.missingTTL = duration
+	return clone
+}
+
+// WithCacheMethods sets the HTTP methods whose responses are cached, replacing
+// the default ones (GET and HEAD). Caching methods that mutate state means
+// that repeated calls may never reach the service, so use it with care.
+func (client *Client) WithCacheMethods(methods ...string) *Client {
+	clone := client.clone()
+	clone.cacheable = make(map[string]bool, len(methods))
+	for _, method := range methods {
+		clone.cacheable[strings.ToUpper(method)] = true
+	}
+	return clone
+}
+
+// WithCacheReaderPolicy sets how calls whose body is an io.Reader are cached.
+// By default, the body is read up front, so it can be part of the cache key,
+// and then sent as read. SkipReaderBodies bypasses the cache for these calls
+// instead, so large or streaming bodies are never held in memory.
+func (client *Client) WithCacheReaderPolicy(policy CacheReaderPolicy) *Client {
+	clone := client.clone()
+	clone.bodyPolicy = policy
+	return clone
 }
 
 // WithBasePath set the client's base path.
 func (client *Client) WithBasePath(path string) *Client {
-	client.basePath = strings.TrimRight(path, uriSeparator)
-	return client
+	clone := client.clone()
+	clone.basePath = strings.TrimRight(path, uriSeparator)
+	return clone
+}
+
+// WithScheme sets the scheme of the calls, either http or https, replacing the
+// one of the base path, if any. Base paths that are a bare host, like
+// "api.example.com", use https by default. Any other scheme is logged and
+// ignored.
+func (client *Client) WithScheme(scheme string) *Client {
+	scheme = strings.ToLower(scheme)
+	if scheme != httpScheme && scheme != httpsScheme {
+		client.logger.Errorf("Invalid scheme %q, it must be %s or %s\n", scheme, httpScheme, httpsScheme)
+		return client
+	}
+
+	clone := client.clone()
+	clone.scheme = scheme
+	return clone
+}
+
+// withScheme sets the client scheme to the base path, or https if it is a
+// bare host without one.
+func (client *Client) withScheme(basePath string) string {
+	if basePath == "" || strings.HasPrefix(basePath, uriSeparator) {
+		return basePath
+	}
+
+	if index := strings.Index(basePath, schemeSeparator); index >= 0 {
+		if client.scheme == "" {
+			return basePath
+		}
+		basePath = basePath[index+len(schemeSeparator):]
+	}
+
+	scheme := client.scheme
+	if scheme == "" {
+		scheme = httpsScheme
+	}
+
+	return scheme + schemeSeparator + basePath
+}
+
+// WithServiceResolver sets a function that resolves the service set with
+// ToService to the host and port to call. It is called on every call, so the
+// client follows the service when it moves, as in a service mesh.
+func (client *Client) WithServiceResolver(resolver func(service string) (string, int, error)) *Client {
+	clone := client.clone()
+	clone.resolver = resolver
+	return clone
 }
 
 // WithPort set the client's port to call.
 func (client *Client) WithPort(port int) *Client {
-	client.port = port
-	return client
+	clone := client.clone()
+	clone.port = port
+	return clone
 }
 
 // ToService set the service destination
 func (client *Client) ToService(service string) *Client {
-	client.service = service
-	return client
+	clone := client.clone()
+	clone.service = service
+	return clone
 }
 
 // WithVersion set the API version
 func (client *Client) WithVersion(version string) *Client {
-	client.version = version
-	return client
+	clone := client.clone()
+	clone.version = version
+	return clone
 }
 
 // WithTimeout set a timeout to the api requests.
 func (client *Client) WithTimeout(duration time.Duration) *Client {
-	client.httpClient.Timeout = duration
-	return client
+	clone := client.clone()
+	clone.httpClient.Timeout = duration
+	return clone
+}
+
+// WithContext sets the context of the calls, like the one of the incoming
+// request, so cancelling it cancels every call in flight. It is also passed
+// to the api key function. A nil context is replaced by context.Background.
+func (client *Client) WithContext(ctx context.Context) *Client {
+	clone := client.clone()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	clone.ctx = ctx
+	return clone
+}
+
+// WithStallTimeout makes reading a response body fail with a
+// *StalledTransferError when no bytes arrive within the provided duration. It
+// detects stalled transfers that a global timeout would not catch.
+func (client *Client) WithStallTimeout(duration time.Duration) *Client {
+	clone := client.clone()
+	clone.stall = duration
+	return clone
+}
+
+// WithMaxRedirects sets the maximum number of redirects the client follows.
+// A call that needs more redirects fails with a *TooManyRedirectsError.
+func (client *Client) WithMaxRedirects(max int) *Client {
+	clone := client.clone()
+	clone.httpClient.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		if len(via) > max {
+			return &TooManyRedirectsError{Max: max}
+		}
+		return nil
+	}
+	return clone
+}
+
+// WithRedirectPolicy sets the function that decides whether the client follows
+// a redirect, as http.Client.CheckRedirect does. It replaces the limit set with
+// WithMaxRedirects.
+func (client *Client) WithRedirectPolicy(policy func(request *http.Request, via []*http.Request) error) *Client {
+	clone := client.clone()
+	clone.httpClient.CheckRedirect = policy
+	return clone
+}
+
+// WithNoRedirects makes the client return the redirect responses as they are,
+// instead of following them, so their Location header can be read.
+func (client *Client) WithNoRedirects() *Client {
+	return client.WithRedirectPolicy(func(request *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	})
+}
+
+// WithCookieJar makes the client store the cookies set by the services in the
+// provided jar, and send them back on the next calls, so session based
+// services can be used.
+func (client *Client) WithCookieJar(jar http.CookieJar) *Client {
+	clone := client.clone()
+	clone.httpClient.Jar = jar
+	return clone
+}
+
+// WithDefaultCookieJar makes the client store and send back cookies in an in
+// memory jar. See WithCookieJar.
+func (client *Client) WithDefaultCookieJar() *Client {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		client.logger.Errorf("Can't initialize the cookie jar, cookies are disabled: %v\n", err)
+		return client
+	}
+
+	return client.WithCookieJar(jar)
+}
+
+// WithMinTLSVersion sets the minimum TLS version accepted by the client, like
+// tls.VersionTLS12. The rest of the TLS configuration is kept.
+func (client *Client) WithMinTLSVersion(version uint16) *Client {
+	clone := client.clone()
+	transport := clone.transport()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	} else {
+		transport.TLSClientConfig = transport.TLSClientConfig.Clone()
+	}
+
+	transport.TLSClientConfig.MinVersion = version
+	return clone
+}
+
+// transport replaces the transport of the client by a copy owned by the
+// client, so it can be configured safely, and returns it. Clients without a
+// transport of their own get a copy of the default one.
+func (client *Client) transport() *http.Transport {
+	transport, ok := client.httpClient.Transport.(*http.Transport)
+	if !ok {
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+
+	transport = transport.Clone()
+	client.httpClient.Transport = transport
+	return transport
+}
+
+// WithRequestValidator sets a function that validates every request body
+// before it is sent. If it returns an error, the call fails early with it and
+// nothing is sent.
+func (client *Client) WithRequestValidator(validator func(body interface{}) error) *Client {
+	clone := client.clone()
+	clone.validator = validator
+	return clone
+}
+
+// WithErrorOnHTTPError makes the call methods return the parsed
+// *ErrorResponse as the error, instead of the response, when the service
+// answers with a 4XX or 5XX status. Use IsErrorResponse to detect it.
+func (client *Client) WithErrorOnHTTPError() *Client {
+	clone := client.clone()
+	clone.errorOnHTTPError = true
+	return clone
 }
 
 // WithAPIKey adds a 'key' parameter to the call query
 func (client *Client) WithAPIKey(key string) *Client {
-	client.apiKey = key
-	return client
+	clone := client.clone()
+	clone.apiKey = key
+	return clone
+}
+
+// WithAPIKeyFunc sets a function that returns the 'key' parameter of each call
+// from the call context, as in multi-tenant services where the key varies per
+// call. It takes precedence over WithAPIKey; an empty key is not added.
+func (client *Client) WithAPIKeyFunc(keyFunc func(ctx context.Context) string) *Client {
+	clone := client.clone()
+	clone.apiKeyFunc = keyFunc
+	return clone
+}
+
+// WithNoAPIKeyForPaths makes the calls whose path starts with any of the
+// provided prefixes, like "/public", skip the 'key' parameter. Prefixes are
+// relative to the client URI, as the call paths are.
+func (client *Client) WithNoAPIKeyForPaths(prefixes ...string) *Client {
+	clone := client.clone()
+	for _, prefix := range prefixes {
+		clone.noKeyPaths = append(clone.noKeyPaths, uriSeparator+strings.TrimLeft(prefix, uriSeparator))
+	}
+	return clone
+}
+
+// Close releases the resources held by the client: it waits for the pending
+// cache writes, closes the cache database, if any, and the idle connections of
+// the HTTP transport. The cache database is shared by the clients derived from
+// the one that enabled it, so only closing that one closes the database; the
+// others just stop using it. It is safe to call Close more than once. When
+// body leak detection is enabled, it returns a *BodyLeakError if any response
+// body was not closed.
+func (client *Client) Close() error {
+	client.httpClient.CloseIdleConnections()
+	client.writer.close()
+
+	err := client.closeCache()
+	if err != nil {
+		return err
+	}
+
+	return client.checkBodyLeaks()
+}
+
+func (client *Client) closeCache() error {
+	if client.cacheDB == nil || !client.ownsCache {
+		client.cacheDB = nil
+		return nil
+	}
+
+	err := client.cacheDB.Close()
+	client.cacheDB = nil
+	return err
+}
+
+// WithPathParams sets the values of the {name} placeholders of the call paths,
+// so client.GET("/posts/{id}/comments", nil) calls /posts/1/comments when
+// params is {"id": "1"}. Values are path escaped. Placeholders without a value
+// are left untouched.
+func (client *Client) WithPathParams(params map[string]string) *Client {
+	clone := client.clone()
+	clone.pathParams = params
+	return clone
+}
+
+func (client *Client) expandPath(path string) string {
+	for name, value := range client.pathParams {
+		path = strings.Replace(path, "{"+name+"}", url.PathEscape(value), -1)
+	}
+	return path
 }
 
 // GetFullPath returns the full path to the service base URL
@@ -104,22 +510,56 @@ func (client *Client) GetFullPath() string {
 	return client.getURI()
 }
 
-// GET performs a secure GET petition. Final URI will be client base path + provided path
-func (client *Client) GET(path string, body interface{}, query map[string][]string) (*http.Response, error) {
-	return client.executeCall(http.MethodGet, path, body, query)
+// GET performs a secure GET petition. Final URI will be client base path + provided path.
+// The query is optional; when several are provided, they are merged.
+func (client *Client) GET(path string, body interface{}, query ...map[string][]string) (*http.Response, error) {
+	return client.executeCall(http.MethodGet, path, body, MergeQueries(query...))
+}
+
+// GetInto performs a GET petition and parses the response body to the
+// receiver, as ParseResponseTo does.
+func (client *Client) GetInto(path string, receiver interface{}, query map[string][]string) error {
+	_, err := client.GetIntoResp(path, receiver, query)
+	return err
 }
 
-// POST performs a secure POST petition. Final URI will be client base path + provided path
-func (client *Client) POST(path string, body interface{}, query map[string][]string) (*http.Response, error) {
-	return client.executeCall(http.MethodPost, path, body, query)
+// GetIntoResp is like GetInto, but also returns the response, so its status
+// and headers can be inspected. Its body is already consumed and closed.
+func (client *Client) GetIntoResp(path string, receiver interface{}, query map[string][]string) (*http.Response, error) {
+	resp, err := client.GET(path, nil, query)
+	if err != nil {
+		return resp, err
+	}
+	defer resp.Body.Close()
+
+	return resp, ParseResponseTo(resp, receiver)
+}
+
+// POST performs a secure POST petition. Final URI will be client base path + provided path.
+// The query is optional; when several are provided, they are merged.
+func (client *Client) POST(path string, body interface{}, query ...map[string][]string) (*http.Response, error) {
+	return client.executeCall(http.MethodPost, path, body, MergeQueries(query...))
+}
+
+// POSTForm performs a secure POST petition with the form url-encoded in the
+// body, setting content type to be application/x-www-form-urlencoded.
+// Final URI will be client base path + provided path
+func (client *Client) POSTForm(path string, form url.Values, query map[string][]string) (*http.Response, error) {
+	headers := client.snapshotHeaders().Clone()
+	headers.Set(contentTypeHeader, formContent)
+	return client.executeCallWithHeaders(http.MethodPost, path, strings.NewReader(form.Encode()), query, headers)
 }
 
 // MultipartBody models the body of a multipart POST call, where:
 // files: a map in with the key represent the form key, and the value represents the path to the file.
 // params: A map with the key-values to be send in the body with the files.
+// fileReaders: a map in with the key represent the form key, and the value the content of the file.
+// progress: an optional function called with the bytes of the files written so far.
 type MultipartBody struct {
-	Params map[string]string
-	Files  map[string]string
+	Params      map[string]string
+	Files       map[string]string
+	FileReaders map[string]io.Reader
+	Progress    ProgressFunc
 }
 
 // NewMultipartBody returns a new struct with desired values attached.
@@ -130,6 +570,31 @@ func NewMultipartBody(params map[string]string, files map[string]string) Multipa
 	}
 }
 
+// NewMultipartBodyWithReaders returns a new struct with the files read from
+// the provided readers instead of from disk. The filename of each part is the
+// one of the reader if it has a Name method, like *os.File or the readers
+// returned by NamedReader, or its form key otherwise.
+func NewMultipartBodyWithReaders(params map[string]string, files map[string]io.Reader) MultipartBody {
+	return MultipartBody{
+		Params:      params,
+		FileReaders: files,
+	}
+}
+
+// NamedReader attaches a filename to reader, to be used in multipart bodies.
+func NamedReader(name string, reader io.Reader) io.Reader {
+	return &namedReader{Reader: reader, name: name}
+}
+
+type namedReader struct {
+	io.Reader
+	name string
+}
+
+func (reader *namedReader) Name() string {
+	return reader.name
+}
+
 // MULTIPART performs a secure POST petition setting content type to be multipart/form-data.
 // Final URI will be client base path + provided path
 // You will need to provide the content type with boundary in formDataContentType.
@@ -144,143 +609,297 @@ func (client *Client) MULTIPART(
 		return nil, err
 	}
 
-	headers := client.headers.Clone()
-	client.headers.Set(contentTypeHeader, formDataContentType)
-	resp, err := client.executeCall(http.MethodPost, path, body, query)
-	client.headers = headers
-	return resp, err
+	headers := client.snapshotHeaders().Clone()
+	headers.Set(contentTypeHeader, formDataContentType)
+	return client.executeCallWithHeaders(http.MethodPost, path, body, query, headers)
 }
 
-func (client *Client) getMultipartBody(data MultipartBody) (body *bytes.Buffer, contentType string, err error) {
-	body = &bytes.Buffer{}
+func (client *Client) getMultipartBody(data MultipartBody) (*bytes.Buffer, string, error) {
+	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
-	for key, path := range data.Files {
-		var file *os.File
-		file, err = os.Open(path)
+	err := writeMultipartBody(writer, data)
+	closeErr := writer.Close()
+	if err != nil {
+		return nil, "", err
+	}
+	if closeErr != nil {
+		return nil, "", closeErr
+	}
+
+	return body, writer.FormDataContentType(), nil
+}
+
+// writeMultipartBody writes the files, from paths and then from readers, and
+// then the params, each sorted by key, so the body has a stable part order.
+func writeMultipartBody(writer *multipart.Writer, data MultipartBody) error {
+	progress := newMultipartProgress(data)
+
+	for _, key := range sortedKeys(data.Files) {
+		path := data.Files[key]
+		err := writeMultipartFile(writer, key, path, progress)
+		if err != nil {
+			return fmt.Errorf("can't add file %v to multipart field %q: %w", path, key, err)
+		}
+	}
+
+	readerKeys := make([]string, 0, len(data.FileReaders))
+	for key := range data.FileReaders {
+		readerKeys = append(readerKeys, key)
+	}
+	sort.Strings(readerKeys)
+
+	for _, key := range readerKeys {
+		reader := data.FileReaders[key]
+		err := writeMultipartPart(writer, key, readerFilename(key, reader), reader, progress)
 		if err != nil {
-			return
+			return fmt.Errorf("can't add reader to multipart field %q: %w", key, err)
 		}
+	}
 
-		var part io.Writer
-		part, err = writer.CreateFormFile(key, filepath.Base(path))
+	for _, key := range sortedKeys(data.Params) {
+		err := writer.WriteField(key, data.Params[key])
 		if err != nil {
-			return
+			return fmt.Errorf("can't add multipart field %q: %w", key, err)
 		}
-		_, err = io.Copy(part, file)
-		file.Close()
 	}
 
-	for key, val := range data.Params {
-		_ = writer.WriteField(key, val)
+	return nil
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
 	}
-	err = writer.Close()
+	sort.Strings(keys)
+
+	return keys
+}
+
+func writeMultipartFile(writer *multipart.Writer, key, path string, progress *multipartProgress) error {
+	file, err := os.Open(path)
 	if err != nil {
-		return
+		return err
+	}
+	defer file.Close()
+
+	return writeMultipartPart(writer, key, filepath.Base(path), file, progress)
+}
+
+func writeMultipartPart(
+	writer *multipart.Writer,
+	key, filename string,
+	content io.Reader,
+	progress *multipartProgress,
+) error {
+
+	part, err := writer.CreateFormFile(key, filename)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(progress.track(part), content)
+	return err
+}
+
+func readerFilename(key string, reader io.Reader) string {
+	if named, ok := reader.(interface{ Name() string }); ok {
+		return filepath.Base(named.Name())
 	}
 
-	contentType = writer.FormDataContentType()
-	return
+	return key
 }
 
-// PUT performs a secure PUT petition. Final URI will be client base path + provided path
-func (client *Client) PUT(path string, body interface{}, query map[string][]string) (*http.Response, error) {
-	return client.executeCall(http.MethodPut, path, body, query)
+// PUT performs a secure PUT petition. Final URI will be client base path + provided path.
+// The query is optional; when several are provided, they are merged.
+func (client *Client) PUT(path string, body interface{}, query ...map[string][]string) (*http.Response, error) {
+	return client.executeCall(http.MethodPut, path, body, MergeQueries(query...))
 }
 
-// DELETE performs a secure DELETE petition. Final URI will be client base path + provided path
-func (client *Client) DELETE(path string, body interface{}, query map[string][]string) (*http.Response, error) {
-	return client.executeCall(http.MethodDelete, path, body, query)
+// DELETE performs a secure DELETE petition. Final URI will be client base path + provided path.
+// The query is optional; when several are provided, they are merged.
+func (client *Client) DELETE(path string, body interface{}, query ...map[string][]string) (*http.Response, error) {
+	return client.executeCall(http.MethodDelete, path, body, MergeQueries(query...))
 }
 
 func (client *Client) executeCall(method, path string, body interface{}, query map[string][]string) (*http.Response, error) {
-	if response, isCached := client.callCached(method, path, body, query); isCached {
-		client.logger.Debugf("Cached response for [%s] %s\n", method, path)
-		return response, nil
-	}
+	return client.executeCallWithHeaders(method, path, body, query, client.snapshotHeaders())
+}
+
+func (client *Client) executeCallWithHeaders(
+	method, path string,
+	body interface{},
+	query map[string][]string,
+	headers http.Header,
+) (*http.Response, error) {
 
-	bodyReader, err := client.interface2Reader(body)
+	path = client.expandPath(path)
+	endpoint, err := client.endpoint(path)
 	if err != nil {
 		return nil, err
 	}
 
-	endpoint, err := url.Parse(fmt.Sprintf("%v%v", client.getURI(), strings.TrimLeft(path, uriSeparator)))
+	return client.execute(method, endpoint, path, body, query, headers)
+}
+
+// execute sends a call to the endpoint. The path identifies the call in the
+// cache, the logs and the fallback.
+func (client *Client) execute(
+	method string,
+	endpoint *url.URL,
+	path string,
+	body interface{},
+	query map[string][]string,
+	headers http.Header,
+) (*http.Response, error) {
+
+	body, err := client.bufferCachedBody(method, body)
 	if err != nil {
 		return nil, err
 	}
 
-	client.addQuery(endpoint, query)
-	request, err := http.NewRequest(method, endpoint.String(), bodyReader)
+	request, err := client.newRequest(method, endpoint, body, query, headers)
 	if err != nil {
 		return nil, err
 	}
 
-	client.injectHeaders(request)
-	response, err := client.do(request)
+	request, span := client.startSpan(request)
+	client.meterStart(request)
+	start := time.Now()
+	response, err := client.send(request, path, body, query, headers)
+	client.observe(method, response, time.Since(start))
+	client.meterEnd(request, response, err, time.Since(start))
+	client.endSpan(span, response, err)
 	if err != nil {
-		return nil, err
+		response, err = client.fallbackResponse(request, path, err)
 	}
 
-	client.cache(method, path, body, query, response)
-	return response, nil
+	if err == nil && client.errorOnHTTPError && !IsSuccess(response) {
+		defer response.Body.Close()
+		return nil, parseError(response)
+	}
+
+	return response, err
 }
 
-func (client *Client) callCached(method, path string, body interface{}, query map[string][]string) (*http.Response, bool) {
-	if client.cacheDB == nil {
-		return nil, false
+// endpoint returns the URL of a call to the path, under the client URI.
+func (client *Client) endpoint(path string) (*url.URL, error) {
+	URI, err := client.callURI()
+	if err != nil {
+		return nil, err
 	}
-	key := getCacheKey(method, path, body, query)
-	response := new(http.Response)
-	err := client.cacheDB.View(getResponseFromCache(response, key))
-	return response, err != nil
+
+	return url.Parse(fmt.Sprintf("%v%v", URI, strings.TrimLeft(path, uriSeparator)))
 }
 
-func getCacheKey(method, path string, body interface{}, query map[string][]string) []byte {
-	key := make([]byte, 0)
+func (client *Client) newRequest(
+	method string,
+	endpoint *url.URL,
+	body interface{},
+	query map[string][]string,
+	headers http.Header,
+) (*http.Request, error) {
 
-	key = appendBytes(key, method)
-	key = appendBytes(key, path)
-	key = appendBytes(key, body)
-	key = appendBytes(key, query)
+	err := client.validateBody(body)
+	if err != nil {
+		return nil, err
+	}
 
-	return key
-}
+	bodyReader, err := client.interface2Reader(body, headers.Get(contentTypeHeader))
+	if err != nil {
+		return nil, err
+	}
 
-func appendBytes(key []byte, value interface{}) []byte {
-	b, _ := json.Marshal(value)
-	return append(key, b...)
+	client.addQuery(client.ctx, endpoint, query)
+	request, err := http.NewRequestWithContext(client.ctx, method, endpoint.String(), bodyReader)
+	if err != nil {
+		return nil, err
+	}
+
+	// Multipart streams know their size upfront, but http.NewRequest can't
+	// recognize them, unlike the readers of the standard library.
+	if stream, ok := bodyReader.(*multipartStream); ok && stream.Size() > 0 {
+		request.ContentLength = stream.Size()
+	}
+
+	injectHeaders(request, headers)
+	if _, isReader := body.(io.Reader); body != nil && !isReader && request.Header.Get(contentTypeHeader) == "" {
+		request.Header.Set(contentTypeHeader, client.getCodec().ContentType())
+	}
+
+	err = client.compressBody(request)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.setBodyChecksum(request)
+	if err != nil {
+		return nil, err
+	}
+
+	client.trackUploadProgress(request)
+	return client.bindDecoding(request), nil
 }
 
-func getResponseFromCache(response *http.Response, key []byte) func(txn *badger.Txn) error {
-	return func(txn *badger.Txn) error {
-		item, err := txn.Get(key)
-		if err == badger.ErrKeyNotFound {
-			response = nil
-			return nil
-		}
+// send returns the cached response of the request, if any, or sends it to the
+// service.
+func (client *Client) send(
+	request *http.Request,
+	path string,
+	body interface{},
+	query map[string][]string,
+	headers http.Header,
+) (*http.Response, error) {
 
-		err = item.Value(func(val []byte) error {
-			return json.Unmarshal(val, &response)
-		})
+	method := request.Method
+	client.logRequest(request)
 
-		return err
+	if response, isCached := client.callCached(method, path, body, query, headers); isCached {
+		client.logger.Debugf("Cached response for [%s] %s\n", method, path)
+		response.Request = request
+		client.trackBody(response)
+		return response, nil
+	}
+
+	if client.budget.exceeded() {
+		return nil, ErrBudgetExceeded
+	}
+
+	request, cancel := client.withStallCancel(request)
+	request, trace := client.withHTTPTrace(request)
+	client.notifyRequest(request)
+	start := time.Now()
+	response, err := client.doHedged(request)
+	client.budget.spend(time.Since(start))
+	if err != nil {
+		cancel()
+		return nil, newTransportCallError(request, trace.wrap(err))
 	}
+	client.notifyResponse(response, time.Since(start))
+	client.watchStall(response, cancel)
+	client.decompress(response)
+	client.limitBody(response)
+	client.budget.track(response)
+
+	err = client.cache(method, path, body, query, headers, response)
+	if err != nil {
+		return nil, err
+	}
+
+	client.trackBody(response)
+	return response, nil
 }
 
-func (client *Client) cache(method, path string, body interface{}, query map[string][]string, response *http.Response) {
-	if client.cacheDB == nil {
-		return
+func (client *Client) validateBody(body interface{}) error {
+	if client.validator == nil || body == nil {
+		return nil
 	}
 
-	key := getCacheKey(method, path, body, query)
-	value, _ := json.Marshal(response)
-	client.cacheDB.Update(func(txn *badger.Txn) error {
-		err := txn.Set(key, value)
-		return err
-	})
+	return client.validator(body)
 }
 
-func (client *Client) interface2Reader(data interface{}) (io.Reader, error) {
+func (client *Client) interface2Reader(data interface{}, contentType string) (io.Reader, error) {
 	if data == nil {
 		return nil, nil
 	}
@@ -290,7 +909,12 @@ func (client *Client) interface2Reader(data interface{}) (io.Reader, error) {
 		return reader, nil
 	}
 
-	requestBody, err := json.Marshal(data)
+	marshal := client.getCodec().Marshal
+	if isXMLContent(contentType) {
+		marshal = xml.Marshal
+	}
+
+	requestBody, err := marshal(data)
 	if err != nil {
 		return nil, err
 	}
@@ -298,35 +922,97 @@ func (client *Client) interface2Reader(data interface{}) (io.Reader, error) {
 	return bytes.NewBuffer(requestBody), nil
 }
 
+func isXMLContent(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == xmlContent || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
 func (client *Client) getURI() string {
-	URI := fmt.Sprintf("%v", client.basePath)
+	return client.buildURI(client.basePath, client.port)
+}
+
+// callURI returns the URI of the next call, resolving the service host and
+// port when the client has a service resolver.
+func (client *Client) callURI() (string, error) {
+	if client.resolver == nil || !client.shouldAddService() {
+		return client.getURI(), nil
+	}
 
-	if client.shouldAddPort() {
-		URI = fmt.Sprintf("%v%v%v", URI, portSeparator, client.port)
+	host, port, err := client.resolver(client.service)
+	if err != nil {
+		return "", fmt.Errorf("can't resolve service %q: %w", client.service, err)
 	}
 
-	URI = fmt.Sprintf("%v%v", URI, uriSeparator)
+	return client.buildURI(strings.TrimRight(host, uriSeparator), port), nil
+}
 
+// buildURI joins the base path, port, version and service into the URI the
+// call paths are appended to. Segments are joined by a single slash, whatever
+// slashes they start or end with, and the URI always ends with one, unless it
+// is empty.
+func (client *Client) buildURI(basePath string, port int) string {
+	segments := []string{}
 	if client.shouldAddVersion() {
-		URI = fmt.Sprintf("%v%v%v", URI, client.version, uriSeparator)
+		segments = append(segments, client.version)
 	}
-
 	if client.shouldAddService() {
-		URI = fmt.Sprintf("%v%v%v", URI, client.service, uriSeparator)
+		segments = append(segments, client.service)
+	}
+
+	basePath = client.withScheme(basePath)
+	URI, err := url.Parse(basePath)
+	if err != nil || URI.Host == "" {
+		relative := joinURISegments(append([]string{joinPort(basePath, port)}, segments...)...)
+		if strings.HasPrefix(basePath, uriSeparator) {
+			relative = uriSeparator + relative
+		}
+		return relative
+	}
+
+	if port != 0 {
+		URI.Host = net.JoinHostPort(URI.Hostname(), strconv.Itoa(port))
 	}
-	return URI
+	URI.Path = uriSeparator + joinURISegments(append([]string{URI.Path}, segments...)...)
+	URI.RawPath = ""
+	return URI.String()
 }
 
-func (client *Client) shouldAddPort() bool {
-	return client.port != 0
+func joinPort(basePath string, port int) string {
+	if port == 0 {
+		return basePath
+	}
+
+	return fmt.Sprintf("%v%v%v", strings.TrimRight(basePath, uriSeparator), portSeparator, port)
+}
+
+// joinURISegments joins the non empty segments with a single slash, adding a
+// trailing one.
+func joinURISegments(segments ...string) string {
+	joined := ""
+	for _, segment := range segments {
+		segment = strings.Trim(segment, uriSeparator)
+		if segment != "" {
+			joined += segment + uriSeparator
+		}
+	}
+
+	return joined
 }
 
 func (client *Client) shouldAddVersion() bool {
 	return client.version != ""
 }
 
-func (client *Client) shouldAddAPIKey() bool {
-	return client.apiKey != ""
+func (client *Client) getAPIKey(ctx context.Context) string {
+	if client.apiKeyFunc != nil {
+		return client.apiKeyFunc(ctx)
+	}
+
+	return client.apiKey
 }
 
 func (client *Client) shouldAddService() bool {
@@ -334,14 +1020,20 @@ func (client *Client) shouldAddService() bool {
 }
 
 func (client *Client) do(request *http.Request) (*http.Response, error) {
-	return client.httpClient.Do(request)
+	response, err := client.chain(client.httpClient.Do)(request)
+	if response == nil && err == nil {
+		return nil, ErrNilResponse
+	}
+
+	return response, err
 }
 
 // ------ Generic Getters ------\\
 
-// GetHeaders returns the client actual header
+// GetHeaders returns a copy of the client actual header. Use SetHeader or
+// ApplyHeaders to change them.
 func (client *Client) GetHeaders() http.Header {
-	return client.headers
+	return client.snapshotHeaders().Clone()
 }
 
 // GetBasePath returns the client actual header
@@ -364,28 +1056,77 @@ func (client *Client) GetTimeout() time.Duration {
 	return client.httpClient.Timeout
 }
 
+// GetContext returns the context of the client calls
+func (client *Client) GetContext() context.Context {
+	return client.ctx
+}
+
 // GetPort returns the client port
 func (client *Client) GetPort() int {
 	return client.port
 }
 
-func (client *Client) addQuery(endpoint *url.URL, query map[string][]string) {
-	if query == nil {
-		return
+func (client *Client) logRequest(request *http.Request) {
+	client.logger.Debugf(
+		"Request [%s] %s with headers %v\n",
+		request.Method,
+		client.redactURL(request.URL),
+		client.RedactHeaders(request.Header),
+	)
+}
+
+// redactURL returns the URL with the API key replaced, so it can be logged.
+func (client *Client) redactURL(endpoint *url.URL) string {
+	query := endpoint.Query()
+	if _, ok := query[keyQuery]; !ok {
+		return endpoint.String()
 	}
 
+	redacted := *endpoint
+	query.Set(keyQuery, redactedValue)
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+// addQuery merges, last-wins, the query of the endpoint, the call query and
+// the api key, so the api key always prevails over a 'key' in the call query.
+func (client *Client) addQuery(ctx context.Context, endpoint *url.URL, query map[string][]string) {
 	queryValues, _ := url.ParseQuery(endpoint.RawQuery)
+	merged := MergeQueries(queryValues, query, client.apiKeyQuery(ctx, endpoint))
+	if len(merged) == 0 {
+		return
+	}
 
-	for key, values := range query {
-		for _, value := range values {
-			queryValues.Add(key, value)
-		}
+	endpoint.RawQuery = url.Values(merged).Encode()
+}
+
+// apiKeyQuery returns the 'key' parameter of a call to the endpoint, if any.
+func (client *Client) apiKeyQuery(ctx context.Context, endpoint *url.URL) map[string][]string {
+	key := client.getAPIKey(ctx)
+	if key == "" || !client.needsAPIKey(endpoint) {
+		return nil
+	}
+
+	return map[string][]string{keyQuery: {key}}
+}
+
+// needsAPIKey reports whether the call path of the endpoint, relative to the
+// client URI, is not under any of the WithNoAPIKeyForPaths prefixes.
+func (client *Client) needsAPIKey(endpoint *url.URL) bool {
+	if len(client.noKeyPaths) == 0 {
+		return true
+	}
+
+	path := endpoint.Path
+	if URI, err := url.Parse(client.getURI()); err == nil {
+		path = strings.TrimPrefix(path, strings.TrimRight(URI.Path, uriSeparator))
 	}
 
-	if client.shouldAddAPIKey() {
-		queryValues.Add(keyQuery, client.apiKey)
+	for _, prefix := range client.noKeyPaths {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
 	}
 
-	endpoint.RawQuery = queryValues.Encode()
-	return
+	return true
 }
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type tenantKey struct{}
+
+func TestWithAPIKeyFunc(t *testing.T) {
+	Convey("Given a client whose api key depends on the call tenant", t, func() {
+		var keys []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keys = append(keys, r.URL.Query().Get(keyQuery))
+		}))
+		defer server.Close()
+
+		tenantKeys := map[string]string{"truman": "truman-key", "capote": "capote-key"}
+		client := MakeNewClient().WithBasePath(server.URL).WithAPIKey("static-key").
+			WithAPIKeyFunc(func(ctx context.Context) string {
+				tenant, _ := ctx.Value(tenantKey{}).(string)
+				return tenantKeys[tenant]
+			})
+		query := map[string][]string{"page": {"1"}}
+
+		Convey("When we make calls from two tenant contexts", func() {
+			client.ctx = context.WithValue(context.Background(), tenantKey{}, "truman")
+			_, err := client.GET("/posts", nil, query)
+			So(err, ShouldBeNil)
+			client.ctx = context.WithValue(context.Background(), tenantKey{}, "capote")
+			_, err = client.GET("/posts", nil, query)
+			So(err, ShouldBeNil)
+
+			Convey("Then each call sends the key of its tenant", func() {
+				So(keys, ShouldResemble, []string{"truman-key", "capote-key"})
+			})
+		})
+
+		Convey("When the function yields no key", func() {
+			client.ctx = context.Background()
+			_, err := client.GET("/posts", nil, query)
+			So(err, ShouldBeNil)
+
+			Convey("Then no key is sent", func() {
+				So(keys, ShouldResemble, []string{""})
+			})
+		})
+	})
+}
+
+func TestWithNoAPIKeyForPaths(t *testing.T) {
+	Convey("Given a versioned client whose public paths skip the api key", t, func() {
+		keys := map[string]string{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keys[r.URL.Path] = r.URL.Query().Get(keyQuery)
+		}))
+		defer server.Close()
+
+		client := MakeNewClient().WithBasePath(server.URL).WithVersion("v1").
+			WithAPIKey("static-key").WithNoAPIKeyForPaths("public", "/health")
+		query := map[string][]string{"page": {"1"}}
+
+		Convey("When we call a public and a keyed path", func() {
+			_, err := client.GET("/public/posts", nil, query)
+			So(err, ShouldBeNil)
+			_, err = client.GET("/posts", nil, query)
+			So(err, ShouldBeNil)
+
+			Convey("Then the key is only sent to the keyed path", func() {
+				So(keys["/v1/public/posts"], ShouldBeEmpty)
+				So(keys["/v1/posts"], ShouldEqual, "static-key")
+			})
+		})
+	})
+}
+
+func TestAPIKeyQuery(t *testing.T) {
+	Convey("Given a client with an api key", t, func() {
+		var queries []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			queries = append(queries, r.URL.RawQuery)
+		}))
+		defer server.Close()
+		client := MakeNewClient().WithBasePath(server.URL).WithAPIKey("secret")
+
+		Convey("When we make a call without query", func() {
+			_, err := client.GET("/posts", nil)
+			So(err, ShouldBeNil)
+			_, err = client.GET("/posts", nil, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then the key is sent anyway", func() {
+				So(queries, ShouldResemble, []string{"key=secret", "key=secret"})
+			})
+		})
+
+		Convey("When the call query has its own key", func() {
+			_, err := client.GET("/posts", nil, map[string][]string{keyQuery: {"mine"}, "page": {"1"}})
+			So(err, ShouldBeNil)
+
+			Convey("Then the api key replaces it", func() {
+				So(queries, ShouldResemble, []string{"key=secret&page=1"})
+			})
+		})
+	})
+}
+
+func TestWithMinTLSVersion(t *testing.T) {
+	Convey("Given a client trusting a test TLS server", t, func() {
+		newTLSServer := func(maxVersion uint16) *httptest.Server {
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			server.TLS = &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: maxVersion}
+			server.StartTLS()
+			return server
+		}
+		newTrustingClient := func(server *httptest.Server) *Client {
+			client := MakeNewClient().WithBasePath(server.URL)
+			client.httpClient.Transport = server.Client().Transport.(*http.Transport).Clone()
+			return client
+		}
+
+		Convey("When it requires TLS 1.2 and the server only speaks TLS 1.0", func() {
+			server := newTLSServer(tls.VersionTLS10)
+			defer server.Close()
+			client := newTrustingClient(server).WithMinTLSVersion(tls.VersionTLS12)
+			_, err := client.GET("/posts", nil)
+
+			Convey("Then the connection fails", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When it requires TLS 1.3 and the server speaks up to TLS 1.2", func() {
+			server := newTLSServer(tls.VersionTLS12)
+			defer server.Close()
+			_, errWithoutMin := newTrustingClient(server).GET("/posts", nil)
+			_, errWithMin := newTrustingClient(server).WithMinTLSVersion(tls.VersionTLS13).GET("/posts", nil)
+
+			Convey("Then only the call with the minimum version fails", func() {
+				So(errWithoutMin, ShouldBeNil)
+				So(errWithMin, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When it requires TLS 1.2 and the server speaks TLS 1.2", func() {
+			server := newTLSServer(tls.VersionTLS12)
+			defer server.Close()
+			client := newTrustingClient(server).WithMinTLSVersion(tls.VersionTLS12)
+			_, err := client.GET("/posts", nil)
+
+			Convey("Then the other TLS settings are kept and the call succeeds", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
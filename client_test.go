@@ -1,12 +1,21 @@
 package api_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -178,6 +187,51 @@ func TestToService(t *testing.T) {
 	})
 }
 
+func TestWithServiceResolver(t *testing.T) {
+	Convey("Given a resolver mapping a service name to a local server", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.URL.Path))
+		}))
+		defer server.Close()
+		serverURL, err := url.Parse(server.URL)
+		So(err, ShouldBeNil)
+		port, err := strconv.Atoi(serverURL.Port())
+		So(err, ShouldBeNil)
+
+		errUnknownService := errors.New("unknown service")
+		resolutions := 0
+		resolver := func(service string) (string, int, error) {
+			resolutions++
+			if service != testTargetService {
+				return "", 0, errUnknownService
+			}
+			return "http://" + serverURL.Hostname(), port, nil
+		}
+
+		Convey("When we call the service", func() {
+			client := api.MakeNewClient().WithBasePath("http://unreachable.invalid").
+				ToService(testTargetService).WithServiceResolver(resolver)
+			first := getBody(client)
+			second := getBody(client)
+
+			Convey("Then the call reaches the resolved host on every call", func() {
+				So(first, ShouldEqual, "/truman/posts")
+				So(second, ShouldEqual, "/truman/posts")
+				So(resolutions, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When the service can't be resolved", func() {
+			client := api.MakeNewClient().ToService("capote").WithServiceResolver(resolver)
+			_, err := client.GET(postsEndpoint, nil)
+
+			Convey("Then the call fails with the resolver error", func() {
+				So(errors.Is(err, errUnknownService), ShouldBeTrue)
+			})
+		})
+	})
+}
+
 func TestWithVersion(t *testing.T) {
 	Convey("Given an API version", t, func() {
 		version := testVersion
@@ -208,6 +262,55 @@ func TestWhitTimeout(t *testing.T) {
 	})
 }
 
+func TestWithContext(t *testing.T) {
+	Convey("Given a service that answers once its caller gives up", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		client := api.MakeNewClient().WithBasePath(server.URL).WithContext(ctx)
+
+		Convey("When the client context is cancelled while a call is in flight", func() {
+			time.AfterFunc(10*time.Millisecond, cancel)
+			_, err := client.GET(postsEndpoint, nil)
+
+			Convey("Then the call is cancelled", func() {
+				So(client.GetContext(), ShouldEqual, ctx)
+				So(api.IsCanceledError(err), ShouldBeTrue)
+			})
+		})
+
+		Convey("When the client context is already cancelled", func() {
+			cancel()
+			_, err := client.POST(postsEndpoint, map[string]string{"title": "A sangre fría"})
+
+			Convey("Then the call is not made", func() {
+				So(api.IsCanceledError(err), ShouldBeTrue)
+			})
+		})
+
+		Convey("When a client is derived from it", func() {
+			derived := client.WithAuthHeader(testAuthBearer)
+			cancel()
+			_, err := derived.GET(postsEndpoint, nil)
+
+			Convey("Then its calls are governed by the same context", func() {
+				So(api.IsCanceledError(err), ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given a client without a context", t, func() {
+		client := api.MakeNewClient()
+
+		Convey("Then its calls use the background context", func() {
+			So(client.GetContext(), ShouldResemble, context.Background())
+		})
+	})
+}
+
 func TestGET(t *testing.T) {
 	Convey(givenAClient, t, func() {
 		client := getDefaultTestClient()
@@ -237,6 +340,42 @@ func TestGETSadPath(t *testing.T) {
 	})
 }
 
+func TestGetIntoResp(t *testing.T) {
+	Convey("Given a service that answers a post with a custom header", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Total", "1")
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"id":2,"title":"Desayuno con diamantes"}`))
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we GET the post into a receiver keeping the response", func() {
+			post := new(identifiedPost)
+			resp, err := client.GetIntoResp(postsEndpoint+"/2", post, nil)
+
+			Convey("Then we obtain both the decoded post and the response", func() {
+				So(err, ShouldBeNil)
+				So(post.ID, ShouldEqual, 2)
+				So(post.Title, ShouldEqual, "Desayuno con diamantes")
+				So(resp.StatusCode, ShouldEqual, http.StatusAccepted)
+				So(resp.Header.Get("X-Total"), ShouldEqual, "1")
+			})
+		})
+
+		Convey("When we GET the post into a receiver", func() {
+			post := new(identifiedPost)
+			err := client.GetInto(postsEndpoint+"/2", post, nil)
+
+			Convey("Then we obtain the decoded post", func() {
+				So(err, ShouldBeNil)
+				So(post.ID, ShouldEqual, 2)
+			})
+		})
+	})
+}
+
 func TestPOST(t *testing.T) {
 	Convey(givenAClient, t, func() {
 		client := getDefaultTestClient()
@@ -254,6 +393,264 @@ func TestPOST(t *testing.T) {
 	})
 }
 
+func TestPOSTPartlyReadReader(t *testing.T) {
+	Convey("Given a service that echoes the request body", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(w, r.Body)
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we POST a reader whose start was already read", func() {
+			body := strings.NewReader("header;payload")
+			body.Read(make([]byte, len("header;")))
+			resp, err := client.POST(postsEndpoint, body)
+			So(err, ShouldBeNil)
+			echoed, _ := ioutil.ReadAll(resp.Body)
+
+			Convey("Then only the rest of the reader is sent", func() {
+				So(resp.ContentLength, ShouldEqual, len("payload"))
+				So(string(echoed), ShouldEqual, "payload")
+			})
+		})
+	})
+}
+
+func TestPOSTForm(t *testing.T) {
+	Convey("Given a token endpoint that only accepts url-encoded forms", t, func() {
+		var contentType string
+		var received url.Values
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentType = r.Header.Get("Content-Type")
+			r.ParseForm()
+			received = r.PostForm
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithJSONContent()
+
+		Convey("When we POST a form", func() {
+			form := url.Values{"grant_type": {"password"}, "username": {"truman capote"}}
+			resp, err := client.POSTForm("/token", form, nil)
+
+			Convey("Then the service receives the decoded values", func() {
+				checkResponseIsValid(resp, err)
+				So(contentType, ShouldEqual, "application/x-www-form-urlencoded")
+				So(received, ShouldResemble, form)
+			})
+
+			Convey("And the client content type is restored", func() {
+				So(client.GetHeaders().Get("Content-Type"), ShouldEqual, "application/json")
+			})
+		})
+	})
+}
+
+func TestMULTIPARTConcurrentHeaders(t *testing.T) {
+	Convey("Given a service that records the content type of each call", t, func() {
+		var lock sync.Mutex
+		contentTypes := map[string][]string{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			contentTypes[r.Method] = append(contentTypes[r.Method], r.Header.Get("Content-Type"))
+			lock.Unlock()
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithJSONContent()
+
+		Convey("When we fire MULTIPART and GET calls concurrently", func() {
+			body := api.NewMultipartBody(map[string]string{"title": "Desayuno con diamantes"}, nil)
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					resp, err := client.MULTIPART(postsEndpoint, body, nil)
+					if err == nil {
+						resp.Body.Close()
+					}
+				}()
+				go func() {
+					defer wg.Done()
+					resp, err := client.GET(postsEndpoint, nil)
+					if err == nil {
+						resp.Body.Close()
+					}
+				}()
+			}
+			wg.Wait()
+
+			Convey("Then no call leaks the content type of the other", func() {
+				So(contentTypes[http.MethodGet], ShouldHaveLength, 20)
+				for _, contentType := range contentTypes[http.MethodGet] {
+					So(contentType, ShouldEqual, "application/json")
+				}
+				So(contentTypes[http.MethodPost], ShouldHaveLength, 20)
+				for _, contentType := range contentTypes[http.MethodPost] {
+					So(contentType, ShouldStartWith, "multipart/form-data")
+				}
+				So(client.GetHeaders().Get("Content-Type"), ShouldEqual, "application/json")
+			})
+		})
+	})
+}
+
+func TestMULTIPARTWithReaders(t *testing.T) {
+	Convey("Given a service that reads multipart forms", t, func() {
+		files := map[string]string{}
+		filenames := map[string]string{}
+		var title string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.ParseMultipartForm(1 << 20)
+			title = r.FormValue("title")
+			for key, headers := range r.MultipartForm.File {
+				file, _ := headers[0].Open()
+				content, _ := ioutil.ReadAll(file)
+				file.Close()
+				files[key] = string(content)
+				filenames[key] = headers[0].Filename
+			}
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we upload files from in-memory readers", func() {
+			body := api.NewMultipartBodyWithReaders(
+				map[string]string{"title": "Desayuno con diamantes"},
+				map[string]io.Reader{
+					"cover":    bytes.NewBufferString("generated cover"),
+					"abstract": api.NamedReader("abstract.txt", strings.NewReader("A short novel")),
+				},
+			)
+			resp, err := client.MULTIPART(postsEndpoint, body, nil)
+
+			Convey("Then the service receives their content and filenames", func() {
+				checkResponseIsValid(resp, err)
+				So(title, ShouldEqual, "Desayuno con diamantes")
+				So(files["cover"], ShouldEqual, "generated cover")
+				So(filenames["cover"], ShouldEqual, "cover")
+				So(files["abstract"], ShouldEqual, "A short novel")
+				So(filenames["abstract"], ShouldEqual, "abstract.txt")
+			})
+		})
+	})
+}
+
+func TestMULTIPARTFileErrors(t *testing.T) {
+	Convey("Given a service that reads multipart forms", t, func() {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we upload a file that does not exist", func() {
+			body := api.NewMultipartBody(nil, map[string]string{"cover": "testdata/missing.png"})
+			_, err := client.MULTIPART(postsEndpoint, body, nil)
+
+			Convey("Then the call fails with an error naming the file", func() {
+				So(err, ShouldNotBeNil)
+				So(os.IsNotExist(errors.Unwrap(err)), ShouldBeTrue)
+				So(err.Error(), ShouldContainSubstring, "testdata/missing.png")
+				So(err.Error(), ShouldContainSubstring, `"cover"`)
+				So(calls, ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestMULTIPARTPartOrder(t *testing.T) {
+	Convey("Given a service that records the order of multipart parts", t, func() {
+		var parts []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parts = nil
+			reader, err := r.MultipartReader()
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			for part, err := reader.NextPart(); err == nil; part, err = reader.NextPart() {
+				parts = append(parts, part.FormName())
+			}
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we send the same multipart body several times", func() {
+			body := api.NewMultipartBodyWithReaders(
+				map[string]string{"title": "Desayuno con diamantes", "author": "Truman Capote", "year": "1958"},
+				map[string]io.Reader{"cover": strings.NewReader("cover"), "abstract": strings.NewReader("abstract")},
+			)
+
+			Convey("Then the files come first and each group is sorted by key", func() {
+				for i := 0; i < 5; i++ {
+					body.FileReaders["cover"] = strings.NewReader("cover")
+					body.FileReaders["abstract"] = strings.NewReader("abstract")
+					resp, err := client.MULTIPART(postsEndpoint, body, nil)
+					checkResponseIsValid(resp, err)
+					So(parts, ShouldResemble, []string{"abstract", "cover", "author", "title", "year"})
+				}
+			})
+		})
+	})
+}
+
+func TestMULTIPARTProgress(t *testing.T) {
+	Convey("Given a service that reads multipart forms", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(ioutil.Discard, r.Body)
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		var written, totals []int64
+		progress := func(sent, total int64) {
+			written = append(written, sent)
+			totals = append(totals, total)
+		}
+
+		Convey("When we upload a file with a progress callback", func() {
+			file, err := ioutil.TempFile("", "blackbeard")
+			So(err, ShouldBeNil)
+			defer os.Remove(file.Name())
+			size := int64(1 << 20)
+			_, err = file.Write(bytes.Repeat([]byte("a"), int(size)))
+			So(err, ShouldBeNil)
+			file.Close()
+
+			body := api.NewMultipartBody(map[string]string{"title": "Desayuno con diamantes"}, map[string]string{"file": file.Name()})
+			body.Progress = progress
+			resp, err := client.MULTIPART(postsEndpoint, body, nil)
+
+			Convey("Then the final count equals the file size", func() {
+				checkResponseIsValid(resp, err)
+				So(len(written), ShouldBeGreaterThan, 0)
+				So(written[len(written)-1], ShouldEqual, size)
+				So(totals[0], ShouldEqual, size)
+			})
+		})
+
+		Convey("When we stream a reader of unknown size with a progress callback", func() {
+			reader, writer := io.Pipe()
+			go func() {
+				writer.Write(bytes.Repeat([]byte("a"), 1<<10))
+				writer.Close()
+			}()
+
+			body := api.NewMultipartBodyWithReaders(nil, map[string]io.Reader{"file": reader})
+			body.Progress = progress
+			resp, err := client.MULTIPARTStream(postsEndpoint, body, nil)
+
+			Convey("Then the total is reported as unknown", func() {
+				checkResponseIsValid(resp, err)
+				So(written[len(written)-1], ShouldEqual, 1<<10)
+				So(totals[0], ShouldEqual, -1)
+			})
+		})
+	})
+}
+
 func TestPUT(t *testing.T) {
 	Convey(givenAClient, t, func() {
 		client := getDefaultTestClient()
@@ -285,6 +682,447 @@ func TestDELETE(t *testing.T) {
 	})
 }
 
+func TestOptionalQuery(t *testing.T) {
+	Convey("Given a client to a service that echoes the query", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.URL.RawQuery))
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we make a GET call without query", func() {
+			resp, err := client.GET(postsEndpoint, nil)
+
+			Convey("Then no query is sent", func() {
+				checkResponseIsValid(resp, err)
+				body, _ := ioutil.ReadAll(resp.Body)
+				So(string(body), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When we make a GET call with several queries", func() {
+			resp, err := client.GET(postsEndpoint, nil,
+				map[string][]string{"author": {"typicode"}, "$limit": {"5"}},
+				map[string][]string{"author": {"Truman Capote"}},
+			)
+
+			Convey("Then the queries are merged and the last one wins", func() {
+				checkResponseIsValid(resp, err)
+				body, _ := ioutil.ReadAll(resp.Body)
+				So(string(body), ShouldEqual, "%24limit=5&author=Truman+Capote")
+			})
+		})
+	})
+}
+
+func TestWithPathParams(t *testing.T) {
+	Convey("Given a client to a service that echoes the escaped path", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.URL.EscapedPath()))
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we call a path template with simple params", func() {
+			resp, err := client.WithPathParams(map[string]string{"id": "1"}).GET(postsEndpoint+"/{id}/comments", nil)
+
+			Convey("Then the placeholders are substituted", func() {
+				checkResponseIsValid(resp, err)
+				body, _ := ioutil.ReadAll(resp.Body)
+				So(string(body), ShouldEqual, "/posts/1/comments")
+			})
+		})
+
+		Convey("When the params contain slashes and spaces", func() {
+			params := map[string]string{"id": "1/2", "title": "Desayuno con diamantes"}
+			resp, err := client.WithPathParams(params).GET(postsEndpoint+"/{id}/{title}", nil)
+
+			Convey("Then each value is path escaped", func() {
+				checkResponseIsValid(resp, err)
+				body, _ := ioutil.ReadAll(resp.Body)
+				So(string(body), ShouldEqual, "/posts/1%2F2/Desayuno%20con%20diamantes")
+			})
+		})
+	})
+}
+
+func TestClose(t *testing.T) {
+	Convey("Given a client with cache enabled", t, func() {
+		client := getDefaultTestClient().WithCache()
+
+		Convey("When the client is closed twice", func() {
+			firstErr := client.Close()
+			secondErr := client.Close()
+
+			Convey("Then both calls succeed and the cache is released", func() {
+				So(firstErr, ShouldBeNil)
+				So(secondErr, ShouldBeNil)
+				So(api.IsCacheNotEnabledError(client.ClearCache()), ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given a cached base client and a client derived from it", t, func() {
+		server, calls := newCountingServer()
+		defer server.Close()
+		base := api.MakeNewClient().WithBasePath(server.URL).WithCache()
+		defer base.Close()
+		child := base.WithTimeout(time.Second)
+
+		Convey("When the derived client is closed", func() {
+			So(child.Close(), ShouldBeNil)
+			_, err := base.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+			_, err = base.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then the base client keeps caching", func() {
+				So(atomic.LoadInt32(calls), ShouldEqual, 1)
+				So(base.ClearCache(), ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestGetFullPath(t *testing.T) {
+	Convey("Given clients with different URI settings", t, func() {
+		Convey("When the base path is empty", func() {
+			client := api.MakeNewClient().WithBasePath("")
+
+			Convey("Then the URI has no leading slash", func() {
+				So(client.GetFullPath(), ShouldEqual, "")
+				So(client.WithVersion(testVersion).GetFullPath(), ShouldEqual, testVersion+"/")
+				So(client.WithVersion(testVersion).ToService(testTargetService).GetFullPath(), ShouldEqual, testVersion+"/"+testTargetService+"/")
+			})
+		})
+
+		Convey("When the base path has a trailing slash", func() {
+			client := api.MakeNewClient().WithBasePath(testBasePath + "/api/")
+
+			Convey("Then segments are joined by a single slash", func() {
+				So(client.GetFullPath(), ShouldEqual, testBasePath+"/api/")
+				So(client.WithPort(testPort).GetFullPath(), ShouldEqual, "http://localhost:3000/api/")
+			})
+		})
+
+		Convey("When the version and service have leading and trailing slashes", func() {
+			client := api.MakeNewClient().WithBasePath(testBasePath).WithPort(testPort)
+
+			Convey("Then segments are joined by a single slash", func() {
+				So(client.WithVersion("/v1/").GetFullPath(), ShouldEqual, "http://localhost:3000/v1/")
+				So(client.WithVersion("/v1/").ToService("/truman/").GetFullPath(), ShouldEqual, "http://localhost:3000/v1/truman/")
+				So(client.ToService("/truman/").GetFullPath(), ShouldEqual, "http://localhost:3000/truman/")
+			})
+		})
+
+		Convey("When we call a path with a leading slash", func() {
+			var path string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+			}))
+			defer server.Close()
+			client := api.MakeNewClient().WithBasePath(server.URL + "/").WithVersion("/v1/").ToService(testTargetService)
+			resp, err := client.GET("//"+postsEndpoint, nil)
+
+			Convey("Then the final URL has no double slashes", func() {
+				checkResponseIsValid(resp, err)
+				So(path, ShouldEqual, "/v1/"+testTargetService+postsEndpoint)
+			})
+		})
+	})
+}
+
+func TestWithScheme(t *testing.T) {
+	Convey("Given a client whose base path is a bare host", t, func() {
+		client := api.MakeNewClient().WithBasePath("api.example.com")
+
+		Convey("When no scheme is set", func() {
+			Convey("Then the URI uses https", func() {
+				So(client.GetFullPath(), ShouldEqual, "https://api.example.com/")
+			})
+		})
+
+		Convey("When the http scheme is set", func() {
+			client = client.WithScheme("HTTP")
+
+			Convey("Then the URI uses http", func() {
+				So(client.GetFullPath(), ShouldEqual, "http://api.example.com/")
+			})
+		})
+
+		Convey("When an invalid scheme is set", func() {
+			client = client.WithScheme("ftp")
+
+			Convey("Then it is ignored", func() {
+				So(client.GetFullPath(), ShouldEqual, "https://api.example.com/")
+			})
+		})
+	})
+
+	Convey("Given a client whose base path has a scheme", t, func() {
+		client := api.MakeNewClient().WithBasePath("https://api.example.com").WithPort(testPort)
+
+		Convey("When another scheme is set", func() {
+			client = client.WithScheme("http")
+
+			Convey("Then it replaces the one of the base path", func() {
+				So(client.GetFullPath(), ShouldEqual, "http://api.example.com:3000/")
+			})
+		})
+	})
+
+	Convey("Given an http service", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		Convey("When we call its bare host with the http scheme", func() {
+			client := api.MakeNewClient().WithBasePath(strings.TrimPrefix(server.URL, "http://")).WithScheme("http")
+			resp, err := client.GET(postsEndpoint, nil)
+
+			Convey("Then the final URL uses the configured scheme", func() {
+				checkResponseIsValid(resp, err)
+				So(resp.Request.URL.Scheme, ShouldEqual, "http")
+				So(resp.Request.URL.String(), ShouldEqual, server.URL+postsEndpoint)
+			})
+		})
+	})
+}
+
+func TestImmutableBuilders(t *testing.T) {
+	Convey("Given a base client and a service that echoes the Authorization header", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Header.Get("Authorization")))
+		}))
+		defer server.Close()
+		base := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we derive two clients with different auth headers", func() {
+			truman := base.WithAuthHeader("Bearer truman")
+			capote := base.WithAuthHeader("Bearer capote").WithTimeout(time.Second)
+
+			Convey("Then the base client is untouched", func() {
+				So(base.GetHeaders().Get(authHeader), ShouldBeEmpty)
+				So(truman.GetHeaders().Get(authHeader), ShouldEqual, "Bearer truman")
+				So(capote.GetHeaders().Get(authHeader), ShouldEqual, "Bearer capote")
+			})
+
+			Convey("Then their concurrent calls don't interfere", func() {
+				var wg sync.WaitGroup
+				errs := make(chan error, 40)
+				for i := 0; i < 20; i++ {
+					for client, expected := range map[*api.Client]string{truman: "Bearer truman", capote: "Bearer capote"} {
+						wg.Add(1)
+						go func(client *api.Client, expected string) {
+							defer wg.Done()
+							resp, err := client.GET(postsEndpoint, nil)
+							if err != nil {
+								errs <- err
+								return
+							}
+							defer resp.Body.Close()
+							body, _ := ioutil.ReadAll(resp.Body)
+							if string(body) != expected {
+								errs <- fmt.Errorf("expected %q, got %q", expected, body)
+							}
+						}(client, expected)
+					}
+				}
+				wg.Wait()
+				close(errs)
+
+				So(<-errs, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given a cached base client", t, func() {
+		server, calls := newCountingServer()
+		defer server.Close()
+		base := api.MakeNewClient().WithBasePath(server.URL).WithCache()
+
+		Convey("When we derive a client with a short cache TTL", func() {
+			base.WithCacheTTL(10 * time.Millisecond)
+			_, err := base.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+			time.Sleep(50 * time.Millisecond)
+			_, err = base.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then the responses cached by the base client never expire", func() {
+				So(atomic.LoadInt32(calls), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given a cached base client and a service that echoes the Authorization header", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Header.Get("Authorization")))
+		}))
+		defer server.Close()
+		base := api.MakeNewClient().WithBasePath(server.URL).WithCache()
+		defer base.Close()
+
+		Convey("When two clients derived with different auth headers GET the same path", func() {
+			truman := base.WithAuthHeader("Bearer truman")
+			capote := base.WithAuthHeader("Bearer capote")
+			trumanResp, err := truman.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+			trumanBody, _ := ioutil.ReadAll(trumanResp.Body)
+			capoteResp, err := capote.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+			capoteBody, _ := ioutil.ReadAll(capoteResp.Body)
+
+			Convey("Then each one gets the response for its own credentials", func() {
+				So(string(trumanBody), ShouldEqual, "Bearer truman")
+				So(string(capoteBody), ShouldEqual, "Bearer capote")
+			})
+		})
+	})
+}
+
+func TestWithMaxRedirects(t *testing.T) {
+	Convey("Given a service with a chain of three redirects", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hops, _ := strconv.Atoi(r.URL.Query().Get("hops"))
+			if hops < 3 {
+				http.Redirect(w, r, fmt.Sprintf("%s?hops=%d", postsEndpoint, hops+1), http.StatusFound)
+			}
+		}))
+		defer server.Close()
+
+		Convey("When the client follows at most two redirects", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithMaxRedirects(2)
+			_, err := client.GET(postsEndpoint, nil, nil)
+
+			Convey("Then we obtain a too many redirects error", func() {
+				So(api.IsTooManyRedirectsError(err), ShouldBeTrue)
+			})
+		})
+
+		Convey("When the client follows at most three redirects", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithMaxRedirects(3)
+			resp, err := client.GET(postsEndpoint, nil, nil)
+
+			Convey(validResponse, func() {
+				checkResponseIsValid(resp, err)
+			})
+		})
+	})
+}
+
+func TestWithNoRedirects(t *testing.T) {
+	Convey("Given an auth service that redirects to a callback", t, func() {
+		const callback = "/callback?code=truman"
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/authorize" {
+				http.Redirect(w, r, callback, http.StatusFound)
+			}
+		}))
+		defer server.Close()
+
+		Convey("When a client without redirects calls it", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithNoRedirects()
+			resp, err := client.GET("/authorize", nil)
+
+			Convey("Then we obtain the redirect response", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusFound)
+				So(resp.Header.Get("Location"), ShouldEqual, callback)
+			})
+		})
+
+		Convey("When a client with a custom redirect policy calls it", func() {
+			var followed []string
+			client := api.MakeNewClient().WithBasePath(server.URL).WithRedirectPolicy(
+				func(request *http.Request, via []*http.Request) error {
+					followed = append(followed, request.URL.RequestURI())
+					return nil
+				},
+			)
+			resp, err := client.GET("/authorize", nil)
+
+			Convey("Then the policy decides to follow the redirect", func() {
+				checkResponseIsValid(resp, err)
+				So(followed, ShouldResemble, []string{callback})
+			})
+		})
+	})
+}
+
+func TestWithDefaultCookieJar(t *testing.T) {
+	Convey("Given a service that sets a session cookie on login", t, func() {
+		var session string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/login" {
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "truman", Path: "/"})
+				return
+			}
+			if cookie, err := r.Cookie("session"); err == nil {
+				session = cookie.Value
+			}
+		}))
+		defer server.Close()
+
+		Convey("When a client with a cookie jar logs in and makes another call", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithDefaultCookieJar()
+			checkResponseIsValid(client.POST("/login", nil))
+			resp, err := client.GET(postsEndpoint, nil)
+
+			Convey("Then the session cookie is sent back", func() {
+				checkResponseIsValid(resp, err)
+				So(session, ShouldEqual, "truman")
+			})
+		})
+
+		Convey("When a client without a cookie jar logs in and makes another call", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL)
+			checkResponseIsValid(client.POST("/login", nil))
+			resp, err := client.GET(postsEndpoint, nil)
+
+			Convey("Then no cookie is sent", func() {
+				checkResponseIsValid(resp, err)
+				So(session, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestWithRequestValidator(t *testing.T) {
+	Convey("Given a client that requires a title in the request bodies", t, func() {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+		}))
+		defer server.Close()
+		errMissingTitle := errors.New("title is required")
+		client := api.MakeNewClient().WithBasePath(server.URL).WithRequestValidator(func(body interface{}) error {
+			post, ok := body.(map[string]interface{})
+			if !ok || post["title"] == nil {
+				return errMissingTitle
+			}
+			return nil
+		})
+
+		Convey("When we POST a body without title", func() {
+			_, err := client.POST(postsEndpoint, map[string]interface{}{"author": "Truman Capote"}, nil)
+
+			Convey("Then the call fails before reaching the service", func() {
+				So(err, ShouldEqual, errMissingTitle)
+				So(calls, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When we POST a valid body", func() {
+			resp, err := client.POST(postsEndpoint, map[string]interface{}{"title": "Desayuno con diamantes"}, nil)
+
+			Convey(validResponse, func() {
+				checkResponseIsValid(resp, err)
+				So(calls, ShouldEqual, 1)
+			})
+		})
+	})
+}
+
 func getDefaultTestClient() *api.Client {
 	return api.MakeNewClient().WithBasePath(testBasePath).WithPort(3000)
 }
@@ -310,6 +1148,30 @@ func getNewGinContextWithAuthBearer() (*gin.Context, string) {
 
 func checkResponseIsValid(resp *http.Response, err error) {
 	So(err, ShouldBeNil)
-	So(resp.StatusCode, ShouldBeGreaterThanOrEqualTo, http.StatusOK)
-	So(resp.StatusCode, ShouldBeLessThan, http.StatusBadRequest)
+	So(api.IsSuccess(resp), ShouldBeTrue)
+}
+
+// recordingLogger keeps every logged line, so tests can assert on them.
+type recordingLogger struct {
+	sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) record(format string, args ...interface{}) {
+	l.Lock()
+	defer l.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) output() string {
+	l.Lock()
+	defer l.Unlock()
+	return strings.Join(l.lines, "")
 }
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) { l.record(format, args...) }
+func (l *recordingLogger) Infof(format string, args ...interface{})  { l.record(format, args...) }
+func (l *recordingLogger) Warnf(format string, args ...interface{})  { l.record(format, args...) }
+func (l *recordingLogger) Errorf(format string, args ...interface{}) { l.record(format, args...) }
+func (l *recordingLogger) Fatalf(format string, args ...interface{}) { l.record(format, args...) }
+func (l *recordingLogger) Panicf(format string, args ...interface{}) { l.record(format, args...) }
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+)
+
+// Codec marshals request bodies and unmarshals response bodies, like JSON,
+// msgpack or protobuf codecs do.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	// ContentType is the Content-type of the bodies marshalled by the codec.
+	ContentType() string
+}
+
+// JSONCodec is the default codec, based on encoding/json.
+var JSONCodec Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) ContentType() string {
+	return jsonContent
+}
+
+// WithCodec sets the codec used to marshal the request bodies, and to parse
+// the responses with Body2Interface and the Parse helpers. The Content-type of
+// the calls with a marshalled body is the one of the codec, unless the client
+// sets another one. Requests with an XML Content-type are still marshalled as
+// XML.
+func (client *Client) WithCodec(codec Codec) *Client {
+	clone := client.clone()
+	clone.decoding.codec = codec
+	return clone
+}
+
+func (client *Client) getCodec() Codec {
+	if client.decoding.codec == nil {
+		return JSONCodec
+	}
+
+	return client.decoding.codec
+}
+
+func decodeWithCodec(resp *http.Response, codec Codec, receiver interface{}) error {
+	if !isAPointer(receiver) {
+		return NewNotAPointerError()
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return codec.Unmarshal(body, receiver)
+}
@@ -0,0 +1,95 @@
+package api_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+const base64Content = "application/x-base64-json"
+
+// base64Codec is a trivial codec that encodes JSON as base64.
+type base64Codec struct{}
+
+func (base64Codec) Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(data)), nil
+}
+
+func (base64Codec) Unmarshal(data []byte, v interface{}) error {
+	decoded, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(decoded, v)
+}
+
+func (base64Codec) ContentType() string {
+	return base64Content
+}
+
+func TestWithCodec(t *testing.T) {
+	Convey("Given a service that echoes the request body and content type", t, func() {
+		var contentType string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentType = r.Header.Get("Content-Type")
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Header().Set("Content-Type", contentType)
+			w.Write(body)
+		}))
+		defer server.Close()
+		sent := xmlPost{ID: 1, Title: "Desayuno con diamantes", Author: "Truman Capote"}
+
+		Convey("When we POST with the default codec", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL)
+			resp, err := client.POST(postsEndpoint, sent)
+			So(err, ShouldBeNil)
+			received := new(xmlPost)
+			err = api.ParseResponseTo(resp, received)
+
+			Convey("Then the body is sent as JSON", func() {
+				So(err, ShouldBeNil)
+				So(contentType, ShouldEqual, "application/json")
+				So(*received, ShouldResemble, sent)
+			})
+		})
+
+		Convey("When we POST with a custom codec", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithCodec(base64Codec{})
+			resp, err := client.POST(postsEndpoint, sent)
+			So(err, ShouldBeNil)
+			received := new(xmlPost)
+			err = api.ParseResponse(resp, received)
+
+			Convey("Then the body round-trips through the codec with its content type", func() {
+				So(err, ShouldBeNil)
+				So(contentType, ShouldEqual, base64Content)
+				So(*received, ShouldResemble, sent)
+			})
+		})
+
+		Convey("When we POST with a custom codec and an explicit content type", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).
+				WithCodec(base64Codec{}).WithContentType("text/plain")
+			resp, err := client.POST(postsEndpoint, sent)
+			So(err, ShouldBeNil)
+			data, err := api.Body2Interface(resp)
+
+			Convey("Then the explicit content type wins", func() {
+				So(err, ShouldBeNil)
+				So(contentType, ShouldEqual, "text/plain")
+				So(data.(map[string]interface{})["title"], ShouldEqual, sent.Title)
+			})
+		})
+	})
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const (
+	contentEncodingHeader = "Content-Encoding"
+	contentLengthHeader   = "Content-Length"
+	gzipEncoding          = "gzip"
+)
+
+// DefaultCompressionThreshold is the body size, in bytes, from which request
+// bodies are compressed when request compression is enabled.
+const DefaultCompressionThreshold = 1024
+
+var compressibleMethods = map[string]bool{
+	http.MethodPost:  true,
+	http.MethodPut:   true,
+	http.MethodPatch: true,
+}
+
+// WithRequestCompression gzips the bodies of POST, PUT and PATCH calls larger
+// than the compression threshold, setting the Content-Encoding header. Only
+// bodies already held in memory are compressed: streams, like the ones of
+// MULTIPARTStream, are sent as they are, so they are never buffered.
+func (client *Client) WithRequestCompression() *Client {
+	clone := client.clone()
+	clone.gzipAbove = DefaultCompressionThreshold
+	return clone
+}
+
+// WithCompressionThreshold sets the body size, in bytes, from which request
+// bodies are compressed, and enables request compression.
+func (client *Client) WithCompressionThreshold(threshold int64) *Client {
+	clone := client.clone()
+	clone.gzipAbove = threshold
+	return clone
+}
+
+func (client *Client) compressBody(request *http.Request) error {
+	if client.gzipAbove <= 0 || !compressibleMethods[request.Method] {
+		return nil
+	}
+
+	if request.ContentLength <= client.gzipAbove || request.Header.Get(contentEncodingHeader) != "" {
+		return nil
+	}
+
+	// Bodies that can be replayed are in memory. Others, even of known size,
+	// are streams that would need to be read whole to be compressed.
+	if request.GetBody == nil {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	request.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	compressed := new(bytes.Buffer)
+	writer := gzip.NewWriter(compressed)
+	_, err = writer.Write(body)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	content := compressed.Bytes()
+	request.Body = ioutil.NopCloser(bytes.NewReader(content))
+	request.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(content)), nil
+	}
+	request.ContentLength = int64(len(content))
+	request.Header.Set(contentEncodingHeader, gzipEncoding)
+	return nil
+}
+
+// WithRawEncoding keeps compressed response bodies as the service sends them.
+// By default, gzip bodies that were not transparently decompressed by the
+// transport, as happens when a custom Accept-Encoding header is set, are
+// decompressed by the client.
+func (client *Client) WithRawEncoding() *Client {
+	clone := client.clone()
+	clone.rawEncoding = true
+	return clone
+}
+
+func (client *Client) decompress(response *http.Response) {
+	if client.rawEncoding || response.Uncompressed || response.Body == nil || response.Body == http.NoBody {
+		return
+	}
+
+	if !strings.EqualFold(response.Header.Get(contentEncodingHeader), gzipEncoding) {
+		return
+	}
+
+	response.Body = &gzipBody{body: response.Body}
+	response.Header.Del(contentEncodingHeader)
+	response.Header.Del(contentLengthHeader)
+	response.ContentLength = -1
+	response.Uncompressed = true
+}
+
+// gzipBody decompresses the body on the first read, so empty bodies, as the
+// ones of HEAD calls, don't fail until they are read.
+type gzipBody struct {
+	body   io.ReadCloser
+	reader *gzip.Reader
+	err    error
+}
+
+func (body *gzipBody) Read(p []byte) (int, error) {
+	if body.reader == nil && body.err == nil {
+		body.reader, body.err = gzip.NewReader(body.body)
+	}
+	if body.err != nil {
+		return 0, body.err
+	}
+
+	return body.reader.Read(p)
+}
+
+func (body *gzipBody) Close() error {
+	return body.body.Close()
+}
@@ -0,0 +1,126 @@
+package api_test
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func TestGzipResponses(t *testing.T) {
+	Convey("Given a service answering gzipped JSON", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", "gzip")
+			writer := gzip.NewWriter(w)
+			writer.Write([]byte(`{"title":"Desayuno con diamantes"}`))
+			writer.Close()
+		}))
+		defer server.Close()
+
+		Convey("When we call it with a custom Accept-Encoding header", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL)
+			client.SetHeader("Accept-Encoding", "gzip")
+			resp, err := client.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then the body is decompressed and parses", func() {
+				post := map[string]interface{}{}
+				So(api.ParseResponseTo(resp, &post), ShouldBeNil)
+				So(post["title"], ShouldEqual, "Desayuno con diamantes")
+				So(resp.Header.Get("Content-Encoding"), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When a client with raw encoding calls it", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithRawEncoding()
+			client.SetHeader("Accept-Encoding", "gzip")
+			resp, err := client.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then the body is kept compressed", func() {
+				body, err := ioutil.ReadAll(resp.Body)
+				So(err, ShouldBeNil)
+				So(body[:2], ShouldResemble, []byte{0x1f, 0x8b})
+				So(resp.Header.Get("Content-Encoding"), ShouldEqual, "gzip")
+			})
+		})
+	})
+}
+
+func TestWithRequestCompression(t *testing.T) {
+	Convey("Given a service that decompresses request bodies", t, func() {
+		var encoding string
+		var received map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding = r.Header.Get("Content-Encoding")
+			var body io.Reader = r.Body
+			if encoding == "gzip" {
+				reader, err := gzip.NewReader(r.Body)
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				body = reader
+			}
+			received = nil
+			json.NewDecoder(body).Decode(&received)
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithRequestCompression()
+
+		Convey("When we POST a large JSON body", func() {
+			post := map[string]interface{}{"title": strings.Repeat("Desayuno con diamantes ", 100)}
+			resp, err := client.POST(postsEndpoint, post)
+
+			Convey("Then it is sent compressed and round-trips", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(encoding, ShouldEqual, "gzip")
+				So(received, ShouldResemble, post)
+			})
+		})
+
+		Convey("When we POST a body under the threshold", func() {
+			post := map[string]interface{}{"title": "Desayuno con diamantes"}
+			_, err := client.POST(postsEndpoint, post)
+
+			Convey("Then it is sent as it is", func() {
+				So(err, ShouldBeNil)
+				So(encoding, ShouldBeEmpty)
+				So(received, ShouldResemble, post)
+			})
+		})
+
+		Convey("When we stream a large multipart body", func() {
+			body := api.MultipartBody{Params: map[string]string{"title": strings.Repeat("Desayuno con diamantes ", 100)}}
+			resp, err := client.MULTIPARTStream(postsEndpoint, body, nil)
+
+			Convey("Then it is streamed uncompressed", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(encoding, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When we lower the threshold", func() {
+			client = client.WithCompressionThreshold(10)
+			post := map[string]interface{}{"title": "Desayuno con diamantes"}
+			_, err := client.PUT(postsEndpoint, post)
+
+			Convey("Then small bodies are compressed too", func() {
+				So(err, ShouldBeNil)
+				So(encoding, ShouldEqual, "gzip")
+				So(received, ShouldResemble, post)
+			})
+		})
+	})
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ClientConfig is the serializable configuration of a client, as produced by
+// MarshalConfig. Durations are written as time.Duration strings, like "1.5s".
+// Secrets, like the api key and the sensitive headers, are never included.
+type ClientConfig struct {
+	BasePath         string      `json:"basePath,omitempty"`
+	Port             int         `json:"port,omitempty"`
+	Version          string      `json:"version,omitempty"`
+	Service          string      `json:"service,omitempty"`
+	Headers          http.Header `json:"headers,omitempty"`
+	SensitiveHeaders []string    `json:"sensitiveHeaders,omitempty"`
+	Timeout          string      `json:"timeout,omitempty"`
+	StallTimeout     string      `json:"stallTimeout,omitempty"`
+	Hedging          string      `json:"hedging,omitempty"`
+	MaxResponseSize  int64       `json:"maxResponseSize,omitempty"`
+	CompressAbove    int64       `json:"compressAbove,omitempty"`
+	ErrorOnHTTPError bool        `json:"errorOnHTTPError,omitempty"`
+	RawEncoding      bool        `json:"rawEncoding,omitempty"`
+	LooseNumbers     bool        `json:"looseNumbers,omitempty"`
+}
+
+// MarshalConfig serializes the client configuration to JSON, so it can be
+// persisted and reloaded with NewClientFromJSON.
+func (client *Client) MarshalConfig() ([]byte, error) {
+	sensitive := client.sensitiveHeaders()
+
+	headers := client.snapshotHeaders().Clone()
+	for _, header := range sensitive {
+		headers.Del(header)
+	}
+
+	return json.Marshal(ClientConfig{
+		BasePath:         client.basePath,
+		Port:             client.port,
+		Version:          client.version,
+		Service:          client.service,
+		Headers:          headers,
+		SensitiveHeaders: sensitive,
+		Timeout:          formatDuration(client.httpClient.Timeout),
+		StallTimeout:     formatDuration(client.stall),
+		Hedging:          formatDuration(client.hedging),
+		MaxResponseSize:  client.maxBody,
+		CompressAbove:    client.gzipAbove,
+		ErrorOnHTTPError: client.errorOnHTTPError,
+		RawEncoding:      client.rawEncoding,
+		LooseNumbers:     client.decoding.looseNumbers,
+	})
+}
+
+// NewClientFromJSON returns a new client with the configuration serialized by
+// MarshalConfig.
+func NewClientFromJSON(data []byte) (*Client, error) {
+	config := new(ClientConfig)
+	err := json.Unmarshal(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("Error: %v\nCan't parse client config", err)
+	}
+
+	timeout, err := parseDuration(config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	stall, err := parseDuration(config.StallTimeout)
+	if err != nil {
+		return nil, err
+	}
+	hedging, err := parseDuration(config.Hedging)
+	if err != nil {
+		return nil, err
+	}
+
+	client := MakeNewClient().
+		WithBasePath(config.BasePath).
+		WithPort(config.Port).
+		WithVersion(config.Version).
+		ToService(config.Service).
+		WithSensitiveHeaders(config.SensitiveHeaders...).
+		WithTimeout(timeout).
+		WithStallTimeout(stall).
+		WithHedging(hedging).
+		WithMaxResponseSize(config.MaxResponseSize).
+		WithCompressionThreshold(config.CompressAbove)
+
+	client.ApplyHeaders(func(headers http.Header) {
+		for header, values := range config.Headers {
+			headers[http.CanonicalHeaderKey(header)] = append([]string(nil), values...)
+		}
+	})
+	client.errorOnHTTPError = config.ErrorOnHTTPError
+	client.rawEncoding = config.RawEncoding
+	client.decoding.looseNumbers = config.LooseNumbers
+
+	return client, nil
+}
+
+func formatDuration(duration time.Duration) string {
+	if duration == 0 {
+		return ""
+	}
+
+	return duration.String()
+}
+
+func parseDuration(duration string) (time.Duration, error) {
+	if duration == "" {
+		return 0, nil
+	}
+
+	parsed, err := time.ParseDuration(duration)
+	if err != nil {
+		return 0, fmt.Errorf("Error: %v\nCan't parse client config duration %q", err, duration)
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,64 @@
+package api_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func TestMarshalConfig(t *testing.T) {
+	Convey("Given a configured client", t, func() {
+		client := api.MakeNewClient().
+			WithBasePath(testBasePath).
+			WithPort(testPort).
+			WithVersion(testVersion).
+			ToService(testTargetService).
+			WithTimeout(1500 * time.Millisecond).
+			WithStallTimeout(time.Second).
+			WithMaxResponseSize(1 << 20).
+			WithErrorOnHTTPError().
+			WithLooseNumbers().
+			WithSensitiveHeaders("X-Secret").
+			WithAuthHeader(testAuthBearer).
+			WithAPIKey("api-key")
+		client.SetHeader("X-Secret", "secret")
+		client.SetHeader("X-Tenant", "truman")
+
+		Convey("When we marshal its config and load it back", func() {
+			data, err := client.MarshalConfig()
+			So(err, ShouldBeNil)
+			loaded, err := api.NewClientFromJSON(data)
+			So(err, ShouldBeNil)
+			reloaded, err := loaded.MarshalConfig()
+			So(err, ShouldBeNil)
+
+			Convey("Then the loaded client has the same config", func() {
+				So(string(reloaded), ShouldEqual, string(data))
+				So(loaded.GetBasePath(), ShouldEqual, testBasePath)
+				So(loaded.GetPort(), ShouldEqual, testPort)
+				So(loaded.GetVersion(), ShouldEqual, testVersion)
+				So(loaded.GetService(), ShouldEqual, testTargetService)
+				So(loaded.GetTimeout(), ShouldEqual, 1500*time.Millisecond)
+				So(loaded.GetHeaders().Get("X-Tenant"), ShouldEqual, "truman")
+			})
+
+			Convey("Then the secrets are not included", func() {
+				So(string(data), ShouldNotContainSubstring, testAuthBearer)
+				So(string(data), ShouldNotContainSubstring, "api-key")
+				So(string(data), ShouldNotContainSubstring, `"secret"`)
+				So(loaded.GetHeaders().Get("Authorization"), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When we load an invalid config", func() {
+			_, err := api.NewClientFromJSON([]byte(`{"timeout":"soon"}`))
+
+			Convey("Then it fails", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	originHeader                  = "Origin"
+	requestMethodHeader           = "Access-Control-Request-Method"
+	allowOriginHeader             = "Access-Control-Allow-Origin"
+	allowMethodsHeader            = "Access-Control-Allow-Methods"
+	allowHeadersHeader            = "Access-Control-Allow-Headers"
+	allowCredentialsHeader        = "Access-Control-Allow-Credentials"
+	maxAgeHeader                  = "Access-Control-Max-Age"
+	corsWildcard                  = "*"
+	corsHeaderValueSeparator      = ","
+	allowCredentialsHeaderEnabled = "true"
+)
+
+// CORSResult models the answer of a CORS preflight request.
+type CORSResult struct {
+	// Allowed reports whether the origin may send the requested method.
+	Allowed          bool
+	AllowOrigin      string
+	AllowMethods     []string
+	AllowHeaders     []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+	StatusCode       int
+}
+
+// CheckCORS sends a CORS preflight request to path, as a browser would do
+// before sending a method request from origin, and parses the
+// Access-Control-Allow-* headers of the answer.
+func (client *Client) CheckCORS(path, origin, method string) (CORSResult, error) {
+	headers := client.snapshotHeaders().Clone()
+	headers.Set(originHeader, origin)
+	headers.Set(requestMethodHeader, method)
+
+	resp, err := client.executeCallWithHeaders(http.MethodOptions, path, nil, nil, headers)
+	if err != nil {
+		return CORSResult{}, err
+	}
+	defer resp.Body.Close()
+
+	result := CORSResult{
+		AllowOrigin:      resp.Header.Get(allowOriginHeader),
+		AllowMethods:     splitHeaderValues(resp.Header.Get(allowMethodsHeader)),
+		AllowHeaders:     splitHeaderValues(resp.Header.Get(allowHeadersHeader)),
+		AllowCredentials: resp.Header.Get(allowCredentialsHeader) == allowCredentialsHeaderEnabled,
+		StatusCode:       resp.StatusCode,
+	}
+
+	if maxAge, err := strconv.Atoi(resp.Header.Get(maxAgeHeader)); err == nil {
+		result.MaxAge = time.Duration(maxAge) * time.Second
+	}
+
+	result.Allowed = IsSuccess(resp) && result.allowsOrigin(origin) && result.allowsMethod(method)
+	return result, nil
+}
+
+func (result CORSResult) allowsOrigin(origin string) bool {
+	return result.AllowOrigin == corsWildcard || result.AllowOrigin == origin
+}
+
+func (result CORSResult) allowsMethod(method string) bool {
+	for _, allowed := range result.AllowMethods {
+		if allowed == corsWildcard || strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func splitHeaderValues(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	values := strings.Split(value, corsHeaderValueSeparator)
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+
+	return values
+}
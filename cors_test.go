@@ -0,0 +1,67 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+const allowedOrigin = "https://truman.example"
+
+func TestCheckCORS(t *testing.T) {
+	Convey("Given a CORS-enabled service", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodOptions {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			if r.Header.Get("Origin") == allowedOrigin && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+				w.Header().Set("Access-Control-Max-Age", "600")
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we check an allowed origin and method", func() {
+			result, err := client.CheckCORS(postsEndpoint, allowedOrigin, http.MethodPost)
+
+			Convey("Then the preflight is allowed and its headers parsed", func() {
+				So(err, ShouldBeNil)
+				So(result.Allowed, ShouldBeTrue)
+				So(result.AllowMethods, ShouldResemble, []string{"GET", "POST"})
+				So(result.AllowHeaders, ShouldResemble, []string{"Authorization", "Content-Type"})
+				So(result.AllowCredentials, ShouldBeTrue)
+				So(result.MaxAge, ShouldEqual, 10*time.Minute)
+			})
+		})
+
+		Convey("When we check a not allowed method", func() {
+			result, err := client.CheckCORS(postsEndpoint, allowedOrigin, http.MethodDelete)
+
+			Convey("Then the preflight is not allowed", func() {
+				So(err, ShouldBeNil)
+				So(result.Allowed, ShouldBeFalse)
+			})
+		})
+
+		Convey("When we check a not allowed origin", func() {
+			result, err := client.CheckCORS(postsEndpoint, "https://capote.example", http.MethodGet)
+
+			Convey("Then the preflight is not allowed", func() {
+				So(err, ShouldBeNil)
+				So(result.Allowed, ShouldBeFalse)
+				So(result.AllowOrigin, ShouldBeEmpty)
+			})
+		})
+	})
+}
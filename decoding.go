@@ -0,0 +1,348 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Decoder decodes a response body into a receiver.
+type Decoder interface {
+	Decode(data []byte, receiver interface{}) error
+}
+
+// DecoderFunc adapts an ordinary function to the Decoder interface.
+type DecoderFunc func(data []byte, receiver interface{}) error
+
+// Decode calls f(data, receiver).
+func (f DecoderFunc) Decode(data []byte, receiver interface{}) error {
+	return f(data, receiver)
+}
+
+var (
+	// JSONDecoder decodes JSON bodies.
+	JSONDecoder Decoder = DecoderFunc(json.Unmarshal)
+	// XMLDecoder decodes XML bodies.
+	XMLDecoder Decoder = DecoderFunc(xml.Unmarshal)
+	// RawDecoder copies the body as is into a *[]byte or *string receiver.
+	RawDecoder Decoder = DecoderFunc(decodeRaw)
+)
+
+func decodeRaw(data []byte, receiver interface{}) error {
+	switch raw := receiver.(type) {
+	case *[]byte:
+		*raw = append((*raw)[:0], data...)
+	case *string:
+		*raw = string(data)
+	default:
+		return fmt.Errorf("Can't decode raw body into %T", receiver)
+	}
+
+	return nil
+}
+
+// WithDecoderChain sets the decoders tried, in order, by the Parse helpers on
+// the responses of this client, until one of them succeeds. Without a chain,
+// responses are decoded as JSON.
+func (client *Client) WithDecoderChain(decoders ...Decoder) *Client {
+	clone := client.clone()
+	clone.decoding.decoders = decoders
+	return clone
+}
+
+// WithLooseNumbers makes the Parse helpers accept numbers encoded as strings,
+// like "12.5", for the numeric fields of the receiver. It is meant for APIs
+// that are inconsistent about it. It applies to the default JSON decoding,
+// not to the decoders of a decoder chain.
+func (client *Client) WithLooseNumbers() *Client {
+	clone := client.clone()
+	clone.decoding.looseNumbers = true
+	return clone
+}
+
+var (
+	mediaDecodersMu sync.RWMutex
+	mediaDecoders   = map[string]Decoder{
+		jsonContent: JSONDecoder,
+		xmlContent:  XMLDecoder,
+		"text/xml":  XMLDecoder,
+	}
+)
+
+// RegisterDecoder registers the decoder of a media type, like
+// "application/msgpack", to be selected by WithDefaultAccept. JSON and XML
+// decoders are registered by default.
+func RegisterDecoder(mediaType string, decoder Decoder) {
+	mediaDecodersMu.Lock()
+	defer mediaDecodersMu.Unlock()
+
+	mediaDecoders[strings.ToLower(mediaType)] = decoder
+}
+
+// decoderFor returns the decoder registered for the media type. Structured
+// syntax suffixes, like application/vnd.api+json, fall back to the decoder of
+// their base format.
+func decoderFor(mediaType string) (Decoder, bool) {
+	mediaType, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return nil, false
+	}
+
+	mediaDecodersMu.RLock()
+	defer mediaDecodersMu.RUnlock()
+
+	if decoder, ok := mediaDecoders[mediaType]; ok {
+		return decoder, true
+	}
+
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return mediaDecoders[jsonContent], true
+	case strings.HasSuffix(mediaType, "+xml"):
+		return mediaDecoders[xmlContent], true
+	}
+
+	return nil, false
+}
+
+// WithDefaultAccept sets the Accept header to the provided media type and, if
+// there is a decoder registered for it, makes the Parse helpers decode the
+// responses of this client with it, replacing any decoder chain.
+func (client *Client) WithDefaultAccept(mediaType string) *Client {
+	clone := client.clone()
+	clone.headers.Set(acceptHeader, mediaType)
+	if decoder, ok := decoderFor(mediaType); ok {
+		clone.decoding.decoders = []Decoder{decoder}
+	}
+	return clone
+}
+
+// decodeOptions holds the client decoding configuration. It travels with each
+// request context, so the package level Parse helpers can honor it through
+// the response.
+type decodeOptions struct {
+	decoders     []Decoder
+	looseNumbers bool
+	codec        Codec
+}
+
+func (options decodeOptions) isDefault() bool {
+	return len(options.decoders) == 0 && !options.looseNumbers && options.codec == nil
+}
+
+type decodeOptionsKey struct{}
+
+func (client *Client) bindDecoding(request *http.Request) *http.Request {
+	if client.decoding.isDefault() {
+		return request
+	}
+
+	options := client.decoding
+	return request.WithContext(context.WithValue(request.Context(), decodeOptionsKey{}, &options))
+}
+
+func responseDecoding(resp *http.Response) *decodeOptions {
+	if resp == nil || resp.Request == nil {
+		return nil
+	}
+
+	options, _ := resp.Request.Context().Value(decodeOptionsKey{}).(*decodeOptions)
+	return options
+}
+
+// decodeWithChain reads the whole body and tries each decoder in order. When
+// all of them fail, the error of the last one is returned.
+func decodeWithChain(resp *http.Response, decoders []Decoder, receiver interface{}) error {
+	if !isAPointer(receiver) {
+		return NewNotAPointerError()
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	for _, decoder := range decoders {
+		err = decoder.Decode(body, receiver)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Error: %v\nNo decoder could parse response data: %s", err, body)
+}
+
+// coerceNumbers walks the decoded data following the receiver type, replacing
+// the strings found where the receiver expects a number by a json.Number.
+func coerceNumbers(data interface{}, receiverType reflect.Type) interface{} {
+	receiverType = indirectType(receiverType)
+	if reflect.PtrTo(receiverType).Implements(jsonUnmarshalerType) {
+		return data
+	}
+
+	switch receiverType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if number, ok := data.(string); ok {
+			if _, err := strconv.ParseFloat(strings.TrimSpace(number), 64); err == nil {
+				return json.Number(strings.TrimSpace(number))
+			}
+		}
+
+	case reflect.Struct:
+		if object, ok := data.(map[string]interface{}); ok {
+			fields := jsonFields(receiverType)
+			for key, value := range object {
+				if fieldType, ok := lookupJSONField(fields, key); ok {
+					object[key] = coerceNumbers(value, fieldType)
+				}
+			}
+		}
+
+	case reflect.Map:
+		if object, ok := data.(map[string]interface{}); ok {
+			for key, value := range object {
+				object[key] = coerceNumbers(value, receiverType.Elem())
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		if array, ok := data.([]interface{}); ok {
+			for i, value := range array {
+				array[i] = coerceNumbers(value, receiverType.Elem())
+			}
+		}
+	}
+
+	return data
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// jsonFields returns the types of the struct fields by their JSON name,
+// flattening embedded structs as encoding/json does.
+func jsonFields(structType reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" && indirectType(field.Type).Kind() == reflect.Struct {
+			for embeddedName, embeddedType := range jsonFields(indirectType(field.Type)) {
+				if _, ok := fields[embeddedName]; !ok {
+					fields[embeddedName] = embeddedType
+				}
+			}
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+
+	return fields
+}
+
+// lookupJSONField finds a field by its exact JSON name or, as encoding/json
+// does, by a case-insensitive match.
+func lookupJSONField(fields map[string]reflect.Type, key string) (reflect.Type, bool) {
+	if fieldType, ok := fields[key]; ok {
+		return fieldType, true
+	}
+
+	for name, fieldType := range fields {
+		if strings.EqualFold(name, key) {
+			return fieldType, true
+		}
+	}
+
+	return nil, false
+}
+
+// DecodeStreamInto decodes a streaming JSON response into out, one value at a
+// time, as the body is received. The body can be either a JSON array, whose
+// items are sent, or newline delimited JSON (NDJSON), whose values are sent.
+// The response body is closed, and out is closed when it returns, whatever the
+// outcome, so consumers can just range over out. It returns the first decoding
+// error, if any, or the parsed error of a non valid response.
+func DecodeStreamInto[T any](resp *http.Response, out chan<- T) error {
+	defer close(out)
+	defer resp.Body.Close()
+
+	if !IsSuccess(resp) {
+		return parseError(resp)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	decoder := json.NewDecoder(reader)
+
+	isArray, err := startsWithArray(reader)
+	if err != nil {
+		return err
+	}
+
+	if isArray {
+		_, err = decoder.Token()
+		if err != nil {
+			return fmt.Errorf("Error: %v\nCan't read stream start", err)
+		}
+	}
+
+	for decoder.More() {
+		var value T
+		err = decoder.Decode(&value)
+		if err != nil {
+			return fmt.Errorf("Error: %v\nCan't decode stream value", err)
+		}
+		out <- value
+	}
+
+	if isArray {
+		_, err = decoder.Token()
+		if err != nil {
+			return fmt.Errorf("Error: %v\nCan't read stream end", err)
+		}
+	}
+
+	return nil
+}
+
+// startsWithArray peeks the first non blank byte of the body, without
+// consuming it. An empty body is not an array.
+func startsWithArray(reader *bufio.Reader) (bool, error) {
+	for {
+		next, err := reader.Peek(1)
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		if len(bytes.TrimSpace(next)) > 0 {
+			return next[0] == '[', nil
+		}
+
+		_, _ = reader.Discard(1)
+	}
+}
@@ -0,0 +1,278 @@
+package api_test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+type xmlPost struct {
+	ID     int    `json:"id" xml:"id"`
+	Title  string `json:"title" xml:"title"`
+	Author string `json:"author" xml:"author"`
+}
+
+func TestWithDecoderChain(t *testing.T) {
+	Convey("Given a client with a JSON, XML and raw decoder chain", t, func() {
+		server := newStaticServer("application/xml", `<post><id>2</id><title>Desayuno con diamantes</title><author>Truman Capote</author></post>`)
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).
+			WithDecoderChain(api.JSONDecoder, api.XMLDecoder, api.RawDecoder)
+
+		Convey("When we parse an XML response", func() {
+			resp, err := client.GET(postsEndpoint+"/2", nil, nil)
+			So(err, ShouldBeNil)
+			post := new(xmlPost)
+			err = api.ParseResponseTo(resp, post)
+
+			Convey("Then the JSON decoder fails and the XML decoder succeeds", func() {
+				So(err, ShouldBeNil)
+				So(post.ID, ShouldEqual, 2)
+				So(post.Author, ShouldEqual, "Truman Capote")
+			})
+		})
+	})
+
+	Convey("Given a client with a JSON and raw decoder chain", t, func() {
+		server := newStaticServer("text/plain", "Desayuno con diamantes")
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithDecoderChain(api.JSONDecoder, api.RawDecoder)
+
+		Convey("When we parse a plain text response into a string", func() {
+			resp, err := client.GET(postsEndpoint+"/2", nil, nil)
+			So(err, ShouldBeNil)
+			var raw string
+			err = api.ParseResponseTo(resp, &raw)
+
+			Convey("Then the raw decoder is used", func() {
+				So(err, ShouldBeNil)
+				So(raw, ShouldEqual, "Desayuno con diamantes")
+			})
+		})
+	})
+
+	Convey("Given a client with the default decoding", t, func() {
+		server := newStaticServer("application/xml", `<post><id>2</id></post>`)
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we parse an XML response", func() {
+			resp, err := client.GET(postsEndpoint+"/2", nil, nil)
+			So(err, ShouldBeNil)
+			err = api.ParseResponseTo(resp, new(xmlPost))
+
+			Convey("Then it fails as only JSON is decoded", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+// newStaticServer returns a test server that answers every call with the
+// provided content type and body.
+func newStaticServer(contentType, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write([]byte(body))
+	}))
+}
+
+type pricedPost struct {
+	ID    int     `json:"id"`
+	Price float64 `json:"price"`
+	Stock int     `json:"stock"`
+	Code  string  `json:"code"`
+}
+
+func TestWithLooseNumbers(t *testing.T) {
+	Convey("Given a service that encodes numbers sometimes as strings", t, func() {
+		server := newStaticServer("application/json", `{"total":2,"data":[
+			{"id":1,"price":"12.5","stock":3,"code":"007"},
+			{"id":"2","price":7,"stock":"4","code":"008"}
+		]}`)
+		defer server.Close()
+
+		Convey("When we parse it with loose numbers", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithLooseNumbers()
+			resp, err := client.GET(postsEndpoint, nil, nil)
+			So(err, ShouldBeNil)
+			posts := []pricedPost{}
+			err = api.ParseAllPaginated(resp, &posts)
+
+			Convey("Then both encodings are decoded into the numeric fields", func() {
+				So(err, ShouldBeNil)
+				So(posts, ShouldResemble, []pricedPost{
+					{ID: 1, Price: 12.5, Stock: 3, Code: "007"},
+					{ID: 2, Price: 7, Stock: 4, Code: "008"},
+				})
+			})
+		})
+
+		Convey("When we parse it with the default decoding", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL)
+			resp, err := client.GET(postsEndpoint, nil, nil)
+			So(err, ShouldBeNil)
+			err = api.ParseAllPaginated(resp, &[]pricedPost{})
+
+			Convey("Then it fails", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestDecodeStreamInto(t *testing.T) {
+	Convey("Given a consumer reading posts from a channel", t, func() {
+		consume := func(body string) ([]identifiedPost, error) {
+			server := newStaticServer("application/json", body)
+			defer server.Close()
+			resp, err := api.MakeNewClient().WithBasePath(server.URL).GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+
+			out := make(chan identifiedPost)
+			errs := make(chan error, 1)
+			go func() {
+				errs <- api.DecodeStreamInto(resp, out)
+			}()
+
+			var posts []identifiedPost
+			for post := range out {
+				posts = append(posts, post)
+			}
+			return posts, <-errs
+		}
+
+		Convey("When the response is a JSON array", func() {
+			posts, err := consume(` [{"id":1,"title":"Desayuno con diamantes"},{"id":2,"title":"A sangre fría"}]`)
+
+			Convey("Then every item reaches the consumer", func() {
+				So(err, ShouldBeNil)
+				So(posts, ShouldResemble, []identifiedPost{
+					{ID: 1, Title: "Desayuno con diamantes"},
+					{ID: 2, Title: "A sangre fría"},
+				})
+			})
+		})
+
+		Convey("When the response is NDJSON", func() {
+			posts, err := consume("{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n")
+
+			Convey("Then every value reaches the consumer", func() {
+				So(err, ShouldBeNil)
+				So(posts, ShouldHaveLength, 3)
+				So(posts[2].ID, ShouldEqual, 3)
+			})
+		})
+
+		Convey("When the stream is broken in the middle", func() {
+			posts, err := consume(`[{"id":1},{"id":`)
+
+			Convey("Then the consumer gets the decoded values and the error", func() {
+				So(err, ShouldNotBeNil)
+				So(posts, ShouldHaveLength, 1)
+			})
+		})
+	})
+
+	Convey("Given an error response", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+		resp, err := api.MakeNewClient().WithBasePath(server.URL).GET(postsEndpoint, nil)
+		So(err, ShouldBeNil)
+
+		Convey("When we decode it into a channel", func() {
+			out := make(chan identifiedPost, 1)
+			err := api.DecodeStreamInto(resp, out)
+			_, open := <-out
+
+			Convey("Then it fails and the channel is closed", func() {
+				So(api.IsErrorResponse(err), ShouldBeTrue)
+				So(open, ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestXMLContent(t *testing.T) {
+	Convey("Given a legacy service that echoes XML posts", t, func() {
+		var contentType string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentType = r.Header.Get("Content-Type")
+			post := new(xmlPost)
+			if err := xml.NewDecoder(r.Body).Decode(post); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			xml.NewEncoder(w).Encode(post)
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithXMLContent()
+
+		Convey("When we POST a struct and parse the response", func() {
+			sent := xmlPost{ID: 1, Title: "Desayuno con diamantes", Author: "Truman Capote"}
+			resp, err := client.POST(postsEndpoint, sent)
+			So(err, ShouldBeNil)
+			received := new(xmlPost)
+			err = api.ParseXMLTo(resp, received)
+
+			Convey("Then the struct round-trips through XML", func() {
+				So(err, ShouldBeNil)
+				So(contentType, ShouldEqual, "application/xml")
+				So(*received, ShouldResemble, sent)
+			})
+		})
+	})
+}
+
+func TestWithDefaultAccept(t *testing.T) {
+	Convey("Given a service that answers XML when asked for it", t, func() {
+		var accept string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accept = r.Header.Get("Accept")
+			w.Header().Set("Content-Type", "application/xml")
+			w.Write([]byte(`<post><id>2</id><title>Desayuno con diamantes</title><author>Truman Capote</author></post>`))
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithDefaultAccept("application/xml")
+
+		Convey("When we parse a response", func() {
+			resp, err := client.GET(postsEndpoint+"/2", nil)
+			So(err, ShouldBeNil)
+			post := new(xmlPost)
+			err = api.ParseResponseTo(resp, post)
+
+			Convey("Then XML was requested and the XML decoder was chosen", func() {
+				So(err, ShouldBeNil)
+				So(accept, ShouldEqual, "application/xml")
+				So(post.ID, ShouldEqual, 2)
+				So(post.Author, ShouldEqual, "Truman Capote")
+			})
+		})
+	})
+
+	Convey("Given a service that answers a custom media type", t, func() {
+		server := newStaticServer("application/vnd.blackbeard+xml", `<post><id>3</id><title>A sangre fría</title></post>`)
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithDefaultAccept("application/vnd.blackbeard+xml")
+
+		Convey("When we parse a response", func() {
+			resp, err := client.GET(postsEndpoint+"/3", nil)
+			So(err, ShouldBeNil)
+			post := new(xmlPost)
+			err = api.ParseResponseTo(resp, post)
+
+			Convey("Then the decoder of its structured syntax suffix is chosen", func() {
+				So(err, ShouldBeNil)
+				So(post.Title, ShouldEqual, "A sangre fría")
+			})
+		})
+	})
+}
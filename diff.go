@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+const diffRoot = "$"
+
+// DiffResponses decodes the JSON bodies of both responses and returns their
+// field-level differences, one per line, in the form "$.path: first != second".
+// An empty result means both bodies are structurally equal. Both bodies are
+// consumed.
+func DiffResponses(a, b *http.Response) ([]string, error) {
+	first, err := Body2Interface(a)
+	if err != nil {
+		return nil, err
+	}
+
+	second, err := Body2Interface(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffValues(diffRoot, first, second, nil), nil
+}
+
+func diffValues(path string, first, second interface{}, diffs []string) []string {
+	switch firstValue := first.(type) {
+	case map[string]interface{}:
+		secondValue, ok := second.(map[string]interface{})
+		if !ok {
+			return appendDiff(diffs, path, first, second)
+		}
+		return diffObjects(path, firstValue, secondValue, diffs)
+
+	case []interface{}:
+		secondValue, ok := second.([]interface{})
+		if !ok {
+			return appendDiff(diffs, path, first, second)
+		}
+		return diffArrays(path, firstValue, secondValue, diffs)
+
+	default:
+		if first != second {
+			return appendDiff(diffs, path, first, second)
+		}
+		return diffs
+	}
+}
+
+func diffObjects(path string, first, second map[string]interface{}, diffs []string) []string {
+	keys := make([]string, 0, len(first)+len(second))
+	for key := range first {
+		keys = append(keys, key)
+	}
+	for key := range second {
+		if _, ok := first[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		keyPath := fmt.Sprintf("%s.%s", path, key)
+		firstValue, inFirst := first[key]
+		secondValue, inSecond := second[key]
+		switch {
+		case !inFirst:
+			diffs = append(diffs, fmt.Sprintf("%s: missing in first, %s in second", keyPath, formatDiffValue(secondValue)))
+		case !inSecond:
+			diffs = append(diffs, fmt.Sprintf("%s: %s in first, missing in second", keyPath, formatDiffValue(firstValue)))
+		default:
+			diffs = diffValues(keyPath, firstValue, secondValue, diffs)
+		}
+	}
+
+	return diffs
+}
+
+func diffArrays(path string, first, second []interface{}, diffs []string) []string {
+	if len(first) != len(second) {
+		diffs = append(diffs, fmt.Sprintf("%s: length %d != %d", path, len(first), len(second)))
+	}
+
+	for i := 0; i < len(first) && i < len(second); i++ {
+		diffs = diffValues(fmt.Sprintf("%s[%d]", path, i), first[i], second[i], diffs)
+	}
+
+	return diffs
+}
+
+func appendDiff(diffs []string, path string, first, second interface{}) []string {
+	return append(diffs, fmt.Sprintf("%s: %s != %s", path, formatDiffValue(first), formatDiffValue(second)))
+}
+
+func formatDiffValue(value interface{}) string {
+	formatted, _ := json.Marshal(value)
+	return string(formatted)
+}
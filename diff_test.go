@@ -0,0 +1,54 @@
+package api_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func TestDiffResponses(t *testing.T) {
+	Convey("Given two responses with different payloads", t, func() {
+		first := newJSONResponse(`{"id":1,"title":"json-server","tags":["a","b"],"author":{"name":"typicode"}}`)
+		second := newJSONResponse(`{"id":1,"title":"Desayuno con diamantes","tags":["a"],"author":{"name":"typicode","born":1924}}`)
+
+		Convey("When we diff them", func() {
+			diffs, err := api.DiffResponses(first, second)
+
+			Convey("Then we obtain the field-level differences", func() {
+				So(err, ShouldBeNil)
+				So(diffs, ShouldResemble, []string{
+					`$.author.born: missing in first, 1924 in second`,
+					`$.tags: length 2 != 1`,
+					`$.title: "json-server" != "Desayuno con diamantes"`,
+				})
+			})
+		})
+	})
+
+	Convey("Given two responses with the same payload", t, func() {
+		first := newJSONResponse(`{"id":1,"title":"json-server"}`)
+		second := newJSONResponse(`{"title":"json-server","id":1}`)
+
+		Convey("When we diff them", func() {
+			diffs, err := api.DiffResponses(first, second)
+
+			Convey("Then there are no differences", func() {
+				So(err, ShouldBeNil)
+				So(diffs, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func newJSONResponse(body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
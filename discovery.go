@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DiscoveryDocument models the document exposed by services at a well-known
+// endpoint, like /.well-known/api, listing their base URL per version.
+type DiscoveryDocument struct {
+	Current  string            `json:"current"`
+	Versions map[string]string `json:"versions"`
+}
+
+// DiscoverBasePath fetches the discovery document at discoveryURL and points
+// the client to the base URL of its version. When the client has no version,
+// the current version of the document is used. The port of the client is
+// reset, as the discovered base URLs include their own.
+func (client *Client) DiscoverBasePath(discoveryURL string) error {
+	document, err := client.getDiscoveryDocument(discoveryURL)
+	if err != nil {
+		return err
+	}
+
+	version := client.version
+	if version == "" {
+		version = document.Current
+	}
+
+	basePath, ok := document.Versions[version]
+	if !ok {
+		return fmt.Errorf("version %q not found in discovery document %v", version, discoveryURL)
+	}
+
+	client.basePath = strings.TrimRight(basePath, uriSeparator)
+	client.port = 0
+	client.version = version
+	return nil
+}
+
+func (client *Client) getDiscoveryDocument(discoveryURL string) (*DiscoveryDocument, error) {
+	request, err := http.NewRequestWithContext(client.ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if !IsSuccess(resp) {
+		return nil, parseError(resp)
+	}
+
+	document := new(DiscoveryDocument)
+	err = json.NewDecoder(resp.Body).Decode(document)
+	if err != nil {
+		return nil, fmt.Errorf("Error: %v\nCan't parse discovery document %v", err, discoveryURL)
+	}
+
+	return document, nil
+}
@@ -0,0 +1,71 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+const discoveryEndpoint = "/.well-known/api"
+
+func TestDiscoverBasePath(t *testing.T) {
+	Convey("Given a service exposing a discovery document", t, func() {
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case discoveryEndpoint:
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"current":"v2","versions":{"v1":"` + server.URL + `/legacy","v2":"` + server.URL + `"}}`))
+			default:
+				w.Write([]byte(r.URL.Path))
+			}
+		}))
+		defer server.Close()
+
+		Convey("When a client without version discovers its base path", func() {
+			client := api.MakeNewClient().WithPort(3000)
+			err := client.DiscoverBasePath(server.URL + discoveryEndpoint)
+
+			Convey("Then it calls the current version", func() {
+				So(err, ShouldBeNil)
+				So(client.GetVersion(), ShouldEqual, "v2")
+				So(client.GetPort(), ShouldEqual, 0)
+				So(getBody(client), ShouldEqual, "/v2/posts")
+			})
+		})
+
+		Convey("When a client pinned to a version discovers its base path", func() {
+			client := api.MakeNewClient().WithVersion("v1")
+			err := client.DiscoverBasePath(server.URL + discoveryEndpoint)
+
+			Convey("Then it calls the base URL of that version", func() {
+				So(err, ShouldBeNil)
+				So(client.GetBasePath(), ShouldEqual, server.URL+"/legacy")
+			})
+		})
+
+		Convey("When a client pinned to an unknown version discovers its base path", func() {
+			client := api.MakeNewClient().WithVersion("v9")
+			err := client.DiscoverBasePath(server.URL + discoveryEndpoint)
+
+			Convey("Then it fails", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "v9")
+			})
+		})
+
+		Convey("When the discovery document does not exist", func() {
+			client := api.MakeNewClient()
+			server.Close()
+			err := client.DiscoverBasePath(server.URL + discoveryEndpoint)
+
+			Convey("Then it fails", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
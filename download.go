@@ -0,0 +1,25 @@
+package api
+
+import (
+	"io"
+	"net/http"
+)
+
+// Download performs a GET petition and streams the response body to dst,
+// without buffering it, returning the bytes written. The call bypasses the
+// cache, as caching it would read the whole body into memory. Non 2XX responses
+// return the parsed error instead. Final URI will be client base path +
+// provided path.
+func (client *Client) Download(path string, query map[string][]string, dst io.Writer) (int64, error) {
+	resp, err := client.WithCacheMethods().GET(path, nil, query)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return 0, parseError(resp)
+	}
+
+	return io.Copy(dst, resp.Body)
+}
@@ -0,0 +1,100 @@
+package api_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+const reportEndpoint = "/reports/1"
+
+func TestDownload(t *testing.T) {
+	Convey("Given a service serving a report file", t, func() {
+		payload := bytes.Repeat([]byte("report line\n"), 1024)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != reportEndpoint {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"name":"NotFound","message":"report not found","code":404}`))
+				return
+			}
+			w.Write(payload)
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we download the report into a buffer", func() {
+			dst := new(bytes.Buffer)
+			written, err := client.Download(reportEndpoint, nil, dst)
+
+			Convey("Then the whole payload is written", func() {
+				So(err, ShouldBeNil)
+				So(written, ShouldEqual, len(payload))
+				So(dst.Bytes(), ShouldResemble, payload)
+			})
+		})
+
+		Convey("When we download a missing report", func() {
+			dst := new(bytes.Buffer)
+			written, err := client.Download("/reports/2", nil, dst)
+
+			Convey("Then it fails with the parsed error", func() {
+				So(written, ShouldEqual, 0)
+				So(dst.Len(), ShouldEqual, 0)
+				So(errors.Is(err, api.ErrNotFound), ShouldBeTrue)
+				So(api.IsErrorResponse(err), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+// signalWriter signals its first write, so a test can tell that a body is
+// being streamed to it before the service finishes sending it.
+type signalWriter struct {
+	content bytes.Buffer
+	written chan struct{}
+}
+
+func (w *signalWriter) Write(p []byte) (int, error) {
+	if w.content.Len() == 0 {
+		close(w.written)
+	}
+	return w.content.Write(p)
+}
+
+func TestDownloadCached(t *testing.T) {
+	Convey("Given a cached client and a service that holds the end of a report", t, func() {
+		dst := &signalWriter{written: make(chan struct{})}
+		streamed := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("first line\n"))
+			w.(http.Flusher).Flush()
+			select {
+			case <-dst.written:
+				streamed = true
+			case <-time.After(2 * time.Second):
+			}
+			w.Write([]byte("last line\n"))
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithCache()
+		defer client.Close()
+
+		Convey("When we download the report", func() {
+			written, err := client.Download(reportEndpoint, nil, dst)
+
+			Convey("Then it is streamed to the writer, not buffered by the cache", func() {
+				So(err, ShouldBeNil)
+				So(streamed, ShouldBeTrue)
+				So(written, ShouldEqual, dst.content.Len())
+				So(dst.content.String(), ShouldEqual, "first line\nlast line\n")
+			})
+		})
+	})
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// CallErrorKind classifies the reason of a failed call.
+type CallErrorKind int
+
+const (
+	// KindTransport is used when the call could not be completed, like a DNS
+	// failure or a refused connection.
+	KindTransport CallErrorKind = iota + 1
+	// KindTimeout is used when the call exceeded its time limit.
+	KindTimeout
+	// KindHTTP is used when the service answered with a 4XX or 5XX status.
+	// The call methods return those responses as they are, so only
+	// CheckResponse sets it.
+	KindHTTP
+)
+
+func (kind CallErrorKind) String() string {
+	switch kind {
+	case KindTransport:
+		return "transport"
+	case KindTimeout:
+		return "timeout"
+	case KindHTTP:
+		return "http"
+	default:
+		return "unknown"
+	}
+}
+
+// CallError models a failed call, so transport failures and error responses can
+// be handled in the same way by branching on Kind. Err holds the underlying
+// error: the transport one, or the parsed *ErrorResponse for KindHTTP errors.
+// The call methods return transport failures as a CallError; pass their result
+// to CheckResponse to get error responses as one too.
+type CallError struct {
+	Kind       CallErrorKind
+	Method     string
+	URL        string
+	StatusCode int
+	Err        error
+}
+
+func (e *CallError) Error() string {
+	if e.Kind == KindHTTP {
+		return fmt.Sprintf("%s error on [%s] %s: status %d: %v", e.Kind, e.Method, e.URL, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("%s error on [%s] %s: %v", e.Kind, e.Method, e.URL, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
+
+// IsCallError checks if the error is, or wraps, a CallError error.
+func IsCallError(err error) bool {
+	var callErr *CallError
+	return errors.As(err, &callErr)
+}
+
+func newTransportCallError(request *http.Request, err error) error {
+	kind := KindTransport
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		kind = KindTimeout
+	}
+
+	return &CallError{
+		Kind:   kind,
+		Method: request.Method,
+		URL:    request.URL.String(),
+		Err:    err,
+	}
+}
+
+// CheckResponse unifies the result of a call into a single error: it returns
+// the call error as is, a KindHTTP *CallError if the response status is not
+// valid, or nil otherwise. The response body is consumed in the error case.
+func CheckResponse(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if IsSuccess(resp) {
+		return nil
+	}
+
+	callError := &CallError{
+		Kind:       KindHTTP,
+		StatusCode: resp.StatusCode,
+		Err:        parseError(resp),
+	}
+	if resp.Request != nil {
+		callError.Method = resp.Request.Method
+		callError.URL = resp.Request.URL.String()
+	}
+
+	return callError
+}
+
+// TooManyRedirectsError is used when a call needs more redirects than allowed.
+type TooManyRedirectsError struct {
+	Max int
+}
+
+func (e *TooManyRedirectsError) Error() string {
+	return fmt.Sprintf("Stopped after %d redirects", e.Max)
+}
+
+// IsTooManyRedirectsError checks if the error is, or wraps, a
+// TooManyRedirectsError error.
+func IsTooManyRedirectsError(err error) bool {
+	var redirectsErr *TooManyRedirectsError
+	return errors.As(err, &redirectsErr)
+}
+
+// IsTimeoutError checks if the error comes from a call that exceeded its time
+// limit, either the client timeout or a context deadline.
+func IsTimeoutError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// IsCanceledError checks if the error comes from a call whose context was
+// cancelled by the caller.
+func IsCanceledError(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
+
+// ErrNilResponse is returned when an interceptor or transport answers a call
+// with neither a response nor an error.
+var ErrNilResponse = errors.New("nil response without error")
+
+// ErrResponseTooLarge is returned when reading a response body larger than
+// the limit set with WithMaxResponseSize.
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// ErrBudgetExceeded is returned once the client has spent the budget set with
+// WithBudget.
+var ErrBudgetExceeded = errors.New("client budget exceeded")
+
+// ErrLinkNotFound is returned when following a link that is not in the Link
+// header of a response.
+var ErrLinkNotFound = errors.New("link not found")
+
+// ErrPaginationLoop is returned by GetAllPagesByLink when the Link header
+// points to a page that was already fetched.
+var ErrPaginationLoop = errors.New("pagination loop")
+
+// ErrUploadStalled is returned by ResumableUpload when the server keeps not
+// acknowledging any new byte.
+var ErrUploadStalled = errors.New("upload stalled")
+
+// ErrNoProxy is returned by Connect when no proxy applies to the tunnel host.
+var ErrNoProxy = errors.New("no proxy configured")
+
+// ErrDNSResolution is matched by the errors of the calls whose host could not
+// be resolved, when the client was built WithHTTPTrace.
+var ErrDNSResolution = errors.New("DNS resolution failed")
+
+// DNSResolutionError is used when the host of a call could not be resolved.
+// It matches ErrDNSResolution and unwraps to the resolver error.
+type DNSResolutionError struct {
+	Host string
+	Err  error
+}
+
+func (e *DNSResolutionError) Error() string {
+	return fmt.Sprintf("%v for %s: %v", ErrDNSResolution, e.Host, e.Err)
+}
+
+// Unwrap returns the underlying resolver error.
+func (e *DNSResolutionError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches ErrDNSResolution.
+func (e *DNSResolutionError) Is(target error) bool {
+	return target == ErrDNSResolution
+}
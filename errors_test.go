@@ -0,0 +1,264 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func TestCallErrorKinds(t *testing.T) {
+	Convey("Given a client to an unreachable service", t, func() {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we make a call", func() {
+			err := api.CheckResponse(client.GET(postsEndpoint, nil, nil))
+
+			Convey("Then we obtain a transport error", func() {
+				So(api.IsCallError(err), ShouldBeTrue)
+				So(err.(*api.CallError).Kind, ShouldEqual, api.KindTransport)
+			})
+		})
+	})
+
+	Convey("Given a client to a slow service", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithTimeout(50 * time.Millisecond)
+
+		Convey("When we make a call", func() {
+			err := api.CheckResponse(client.GET(postsEndpoint, nil, nil))
+
+			Convey("Then we obtain a timeout error", func() {
+				So(api.IsCallError(err), ShouldBeTrue)
+				So(err.(*api.CallError).Kind, ShouldEqual, api.KindTimeout)
+			})
+		})
+	})
+
+	Convey("Given a client to a service that answers with an error", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"name":"NotFound","message":"No record found","code":404}`))
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we make a call", func() {
+			err := api.CheckResponse(client.GET(postsEndpoint, nil, nil))
+
+			Convey("Then we obtain an HTTP error with the parsed error response", func() {
+				So(api.IsCallError(err), ShouldBeTrue)
+				callError := err.(*api.CallError)
+				So(callError.Kind, ShouldEqual, api.KindHTTP)
+				So(callError.StatusCode, ShouldEqual, http.StatusNotFound)
+				So(callError.Method, ShouldEqual, http.MethodGet)
+				So(api.IsErrorResponse(callError.Err), ShouldBeTrue)
+				So(callError.Err.(*api.ErrorResponse).Message, ShouldEqual, "No record found")
+			})
+		})
+	})
+
+	Convey("Given a client to an unreachable service called by a wrapper", t, func() {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When the wrapper wraps the call error", func() {
+			_, err := client.GET(postsEndpoint, nil, nil)
+			wrapped := fmt.Errorf("Can't list the posts: %w", err)
+
+			Convey("Then it is still recognised as a call error", func() {
+				So(api.IsCallError(wrapped), ShouldBeTrue)
+				var callError *api.CallError
+				So(errors.As(wrapped, &callError), ShouldBeTrue)
+				So(callError.Kind, ShouldEqual, api.KindTransport)
+			})
+		})
+	})
+
+	Convey("Given a client to a service that answers successfully", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we make a call", func() {
+			err := api.CheckResponse(client.GET(postsEndpoint, nil, nil))
+
+			Convey("Then we obtain no error", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestWithErrorOnHTTPError(t *testing.T) {
+	Convey("Given a client that returns errors on error responses", t, func() {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithErrorOnHTTPError()
+
+		Convey("When the service answers with a 404", func() {
+			resp, err := client.GET("/wrong", nil, nil)
+
+			Convey("Then we obtain an error response with the status code", func() {
+				So(resp, ShouldBeNil)
+				So(api.IsErrorResponse(err), ShouldBeTrue)
+				So(err.(*api.ErrorResponse).Code, ShouldEqual, http.StatusNotFound)
+			})
+		})
+	})
+}
+
+func TestErrorResponseIsAs(t *testing.T) {
+	Convey("Given a client to a service that answers with a not found error", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"name":"NotFound","message":"No record found","code":404}`))
+		}))
+		defer server.Close()
+
+		Convey("When the error response is returned directly", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithErrorOnHTTPError()
+			_, err := client.GET(postsEndpoint, nil, nil)
+
+			Convey("Then it can be extracted with errors.As and matched with errors.Is", func() {
+				var apiErr *api.ErrorResponse
+				So(errors.As(err, &apiErr), ShouldBeTrue)
+				So(apiErr.Code, ShouldEqual, http.StatusNotFound)
+				So(apiErr.Message, ShouldEqual, "No record found")
+				So(errors.Is(err, api.ErrNotFound), ShouldBeTrue)
+				So(errors.Is(err, api.ErrConflict), ShouldBeFalse)
+			})
+		})
+
+		Convey("When the error response is wrapped in a call error", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL)
+			err := api.CheckResponse(client.GET(postsEndpoint, nil, nil))
+
+			Convey("Then it can still be extracted and matched", func() {
+				var apiErr *api.ErrorResponse
+				So(errors.As(err, &apiErr), ShouldBeTrue)
+				So(apiErr.Message, ShouldEqual, "No record found")
+				So(errors.Is(err, api.ErrNotFound), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestTimeoutAndCanceledErrors(t *testing.T) {
+	Convey("Given a slow service", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer server.Close()
+
+		Convey("When the client timeout fires", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithTimeout(50 * time.Millisecond)
+			_, err := client.GET(postsEndpoint, nil, nil)
+
+			Convey("Then the error is a timeout", func() {
+				So(api.IsTimeoutError(err), ShouldBeTrue)
+				So(api.IsCanceledError(err), ShouldBeFalse)
+			})
+		})
+
+		Convey("When the call context is cancelled", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).Use(
+				func(request *http.Request, next api.Next) (*http.Response, error) {
+					ctx, cancel := context.WithCancel(request.Context())
+					time.AfterFunc(50*time.Millisecond, cancel)
+					return next(request.WithContext(ctx))
+				},
+			)
+			_, err := client.GET(postsEndpoint, nil, nil)
+
+			Convey("Then the error is a cancellation", func() {
+				So(api.IsCanceledError(err), ShouldBeTrue)
+				So(api.IsTimeoutError(err), ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestErrNilResponse(t *testing.T) {
+	Convey("Given a client whose chain answers with neither a response nor an error", t, func() {
+		client := api.MakeNewClient().WithBasePath(testBasePath).Use(
+			func(request *http.Request, next api.Next) (*http.Response, error) {
+				return nil, nil
+			},
+		)
+
+		Convey("When we make a call", func() {
+			resp, err := client.GET(postsEndpoint, nil)
+
+			Convey("Then it fails with ErrNilResponse instead of a nil response", func() {
+				So(resp, ShouldBeNil)
+				So(errors.Is(err, api.ErrNilResponse), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestRespondError(t *testing.T) {
+	Convey("Given an upstream service answering a feathers error", t, func() {
+		upstreamError := `{"name":"NotFound","message":"post not found","code":404,"class_name":"not-found"}`
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(upstreamError))
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithErrorOnHTTPError()
+
+		Convey("When a gateway handler forwards the upstream error", func() {
+			_, err := client.GET(postsEndpoint, nil)
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			api.RespondError(ctx, err)
+
+			Convey("Then the gin response matches the upstream error", func() {
+				So(recorder.Code, ShouldEqual, http.StatusNotFound)
+				var expected, actual map[string]interface{}
+				So(json.Unmarshal([]byte(upstreamError), &expected), ShouldBeNil)
+				So(json.Unmarshal(recorder.Body.Bytes(), &actual), ShouldBeNil)
+				So(actual, ShouldResemble, expected)
+			})
+		})
+
+		Convey("When a gateway handler forwards any other error", func() {
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			api.RespondError(ctx, errors.New("connection refused"))
+
+			Convey("Then the gin response is a 500", func() {
+				So(recorder.Code, ShouldEqual, http.StatusInternalServerError)
+				So(recorder.Body.String(), ShouldContainSubstring, "connection refused")
+			})
+		})
+
+		Convey("When a gateway handler forwards a nil error", func() {
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			api.RespondError(ctx, nil)
+
+			Convey("Then the gin response is a bare 500", func() {
+				So(recorder.Code, ShouldEqual, http.StatusInternalServerError)
+				So(recorder.Body.String(), ShouldEqual, http.StatusText(http.StatusInternalServerError))
+			})
+		})
+	})
+}
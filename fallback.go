@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+)
+
+// WithFallbackResponse sets a function consulted as a last resort when a call
+// can't reach the service, after any retries, so callers can get a usable
+// default, like an empty list, instead of an error. It receives the method and
+// path of the failed call, and its result is returned as the call result.
+// Responses of the service, even error ones, are returned as is.
+func (client *Client) WithFallbackResponse(fallback func(method, path string) (*http.Response, error)) *Client {
+	clone := client.clone()
+	clone.fallback = fallback
+	return clone
+}
+
+func (client *Client) fallbackResponse(request *http.Request, path string, err error) (*http.Response, error) {
+	if client.fallback == nil || !IsCallError(err) {
+		return nil, err
+	}
+
+	client.logger.Warnf("[%s] %s failed, using the fallback response: %v\n", request.Method, path, err)
+	response, err := client.fallback(request.Method, path)
+	if response == nil && err == nil {
+		return nil, ErrNilResponse
+	}
+	if response != nil && response.Request == nil {
+		response.Request = request
+	}
+
+	return response, err
+}
@@ -0,0 +1,61 @@
+package api_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func emptyListFallback(method, path string) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(`[]`)),
+	}, nil
+}
+
+func TestWithFallbackResponse(t *testing.T) {
+	Convey("Given a client with a fallback response", t, func() {
+		var fallbackPath string
+		fallback := func(method, path string) (*http.Response, error) {
+			fallbackPath = path
+			return emptyListFallback(method, path)
+		}
+
+		Convey("When the upstream is down", func() {
+			server := httptest.NewServer(http.NotFoundHandler())
+			server.Close()
+			client := api.MakeNewClient().WithBasePath(server.URL).WithFallbackResponse(fallback)
+			resp, err := client.GET(postsEndpoint, nil)
+
+			Convey("Then we obtain the fallback response", func() {
+				checkResponseIsValid(resp, err)
+				So(fallbackPath, ShouldEqual, postsEndpoint)
+				var posts []map[string]interface{}
+				So(api.ParseResponseTo(resp, &posts), ShouldBeNil)
+				So(posts, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the upstream answers with an error", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+			client := api.MakeNewClient().WithBasePath(server.URL).WithFallbackResponse(fallback)
+			resp, err := client.GET(postsEndpoint, nil)
+
+			Convey("Then we obtain the upstream response", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusInternalServerError)
+				So(fallbackPath, ShouldBeEmpty)
+			})
+		})
+	})
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const updateGoldenKey = "UPDATE_GOLDEN"
+
+// AssertGolden compares the JSON body of the response with the golden file at
+// goldenPath, ignoring formatting and key order. It returns a
+// *GoldenMismatchError listing the differences if they don't match.
+// When the UPDATE_GOLDEN env variable is set, the golden file is (re)written
+// with the normalized body instead. The response body is consumed.
+func AssertGolden(resp *http.Response, goldenPath string) error {
+	body, err := Body2Interface(resp)
+	if err != nil {
+		return err
+	}
+
+	if os.Getenv(updateGoldenKey) != "" {
+		return writeGolden(goldenPath, body)
+	}
+
+	goldenData, err := ioutil.ReadFile(goldenPath)
+	if err != nil {
+		return err
+	}
+
+	var golden interface{}
+	err = json.Unmarshal(goldenData, &golden)
+	if err != nil {
+		return fmt.Errorf("Error: %v\nCan't parse golden file %v", err, goldenPath)
+	}
+
+	diffs := diffValues(diffRoot, golden, body, nil)
+	if len(diffs) > 0 {
+		return &GoldenMismatchError{GoldenPath: goldenPath, Diffs: diffs}
+	}
+
+	return nil
+}
+
+func writeGolden(goldenPath string, body interface{}) error {
+	normalized, err := json.MarshalIndent(body, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(goldenPath, append(normalized, '\n'), 0644)
+}
+
+// GoldenMismatchError is used when a response doesn't match its golden file.
+// Diffs holds the differences, as returned by DiffResponses, from the golden
+// file to the response.
+type GoldenMismatchError struct {
+	GoldenPath string
+	Diffs      []string
+}
+
+func (e *GoldenMismatchError) Error() string {
+	return fmt.Sprintf("Response doesn't match golden file %v:\n%v", e.GoldenPath, strings.Join(e.Diffs, "\n"))
+}
+
+// IsGoldenMismatchError checks if the error is a GoldenMismatchError error.
+func IsGoldenMismatchError(err error) bool {
+	_, ok := err.(*GoldenMismatchError)
+	return ok
+}
@@ -0,0 +1,61 @@
+package api_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+const postsGolden = "testdata/posts.golden.json"
+
+func TestAssertGolden(t *testing.T) {
+	Convey("Given a golden file with the posts", t, func() {
+		Convey("When the response has the same posts with another format", func() {
+			resp := newJSONResponse(`[{"id":1,"title":"json-server","author":"typicode"},
+				{"title":"Desayuno con diamantes","author":"Truman Capote","id":2}]`)
+			err := api.AssertGolden(resp, postsGolden)
+
+			Convey("Then it matches the golden file", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+
+		Convey("When the response has different posts", func() {
+			resp := newJSONResponse(`[{"id":1,"title":"json-server","author":"typicode"},
+				{"id":2,"title":"Breakfast at Tiffany's","author":"Truman Capote"}]`)
+			err := api.AssertGolden(resp, postsGolden)
+
+			Convey("Then we obtain a mismatch error with the differences", func() {
+				So(api.IsGoldenMismatchError(err), ShouldBeTrue)
+				So(err.(*api.GoldenMismatchError).Diffs, ShouldResemble, []string{
+					`$[1].title: "Desayuno con diamantes" != "Breakfast at Tiffany's"`,
+				})
+			})
+		})
+	})
+
+	Convey("Given the golden update mode", t, func() {
+		dir, err := ioutil.TempDir("", "blackbeard-golden")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		os.Setenv("UPDATE_GOLDEN", "true")
+		defer os.Unsetenv("UPDATE_GOLDEN")
+		goldenPath := filepath.Join(dir, "post.golden.json")
+
+		Convey("When we assert a response", func() {
+			err := api.AssertGolden(newJSONResponse(`{"title":"json-server","id":1}`), goldenPath)
+			golden, readErr := ioutil.ReadFile(goldenPath)
+
+			Convey("Then the golden file is written with the normalized body", func() {
+				So(err, ShouldBeNil)
+				So(readErr, ShouldBeNil)
+				So(string(golden), ShouldEqual, "{\n  \"id\": 1,\n  \"title\": \"json-server\"\n}\n")
+			})
+		})
+	})
+}
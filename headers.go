@@ -1,70 +1,324 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 const (
-	authorizationHeader = "Authorization"
-	traceIDHeader       = "X-trace-id"
-	contentTypeHeader   = "Content-type"
+	authorizationHeader  = "Authorization"
+	traceIDHeader        = "X-trace-id"
+	contentTypeHeader    = "Content-type"
+	acceptLanguageHeader = "Accept-Language"
+	acceptHeader         = "Accept"
+	setCookieHeader      = "Set-Cookie"
+	userAgentHeader      = "User-Agent"
+	etagHeader           = "ETag"
 )
 
+// Version is the library version, sent in the default User-Agent header.
+const Version = "0.1.0"
+
+var defaultUserAgent = "BlackBeard/" + Version
+
+const redactedValue = "[REDACTED]"
+
 const (
 	jsonContent      = "application/json"
 	multipartContent = "multipart/form-data"
+	formContent      = "application/x-www-form-urlencoded"
+	xmlContent       = "application/xml"
 )
 
 // WithTraceID sets the X-trace-id header to provided trace id.
 func (client *Client) WithTraceID(id string) *Client {
-	client.headers.Set(traceIDHeader, id)
-	return client
+	clone := client.clone()
+	clone.headers.Set(traceIDHeader, id)
+	return clone
 }
 
 // WithContentType sets the Content-type header to provided content type.
 func (client *Client) WithContentType(content string) *Client {
-	client.headers.Set(contentTypeHeader, content)
-	return client
+	clone := client.clone()
+	clone.headers.Set(contentTypeHeader, content)
+	return clone
 }
 
 // WithJSONContent sets the Content-type header to application/json
 func (client *Client) WithJSONContent() *Client {
-	client.headers.Set(contentTypeHeader, jsonContent)
-	return client
+	clone := client.clone()
+	clone.headers.Set(contentTypeHeader, jsonContent)
+	return clone
+}
+
+// WithXMLContent sets the Content-type header to application/xml, so request
+// bodies are marshalled as XML.
+func (client *Client) WithXMLContent() *Client {
+	clone := client.clone()
+	clone.headers.Set(contentTypeHeader, xmlContent)
+	return clone
+}
+
+// WithLocale sets the Accept-Language header to the provided language tags,
+// in order of preference. Each tag after the first one gets a lower quality
+// value, so WithLocale("es-ES", "en") sends "es-ES, en;q=0.9".
+func (client *Client) WithLocale(tags ...string) *Client {
+	clone := client.clone()
+	if len(tags) == 0 {
+		return clone
+	}
+
+	clone.headers.Set(acceptLanguageHeader, acceptLanguage(tags))
+	return clone
+}
+
+func acceptLanguage(tags []string) string {
+	languages := make([]string, len(tags))
+	for i, tag := range tags {
+		quality := 10 - i
+		if quality < 1 {
+			quality = 1
+		}
+
+		if quality == 10 {
+			languages[i] = tag
+		} else {
+			languages[i] = fmt.Sprintf("%s;q=0.%d", tag, quality)
+		}
+	}
+
+	return strings.Join(languages, ", ")
+}
+
+// WithUserAgent sets the User-Agent header to provided user agent. By default,
+// the client sends BlackBeard/<Version>.
+func (client *Client) WithUserAgent(userAgent string) *Client {
+	clone := client.clone()
+	clone.headers.Set(userAgentHeader, userAgent)
+	return clone
 }
 
 // WithAuthHeader sets the Authorization header to provided token.
 func (client *Client) WithAuthHeader(token string) *Client {
-	client.headers.Set(authorizationHeader, token)
-	return client
+	clone := client.clone()
+	clone.headers.Set(authorizationHeader, token)
+	return clone
+}
+
+// WithHeaders replaces all the headers, including the default User-Agent, by
+// a copy of the provided ones.
+func (client *Client) WithHeaders(headers http.Header) *Client {
+	clone := client.clone()
+	clone.headers = http.Header{}
+	for key, values := range headers {
+		clone.headers[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+	}
+	return clone
 }
 
-func (client *Client) injectHeaders(request *http.Request) {
-	request.Header = client.headers
+// WithSensitiveHeaders marks the provided headers as sensitive, so their values
+// are redacted whenever the client logs or dumps them. The Authorization header
+// is always considered sensitive.
+func (client *Client) WithSensitiveHeaders(headers ...string) *Client {
+	clone := client.clone()
+	if clone.sensitive == nil {
+		clone.sensitive = map[string]bool{}
+	}
+
+	for _, header := range headers {
+		clone.sensitive[http.CanonicalHeaderKey(header)] = true
+	}
+	return clone
+}
+
+// sensitiveHeaders returns the canonical names of the sensitive headers, in
+// order.
+func (client *Client) sensitiveHeaders() []string {
+	sensitive := make([]string, 0, len(client.sensitive))
+	for header := range client.sensitive {
+		sensitive = append(sensitive, header)
+	}
+	sort.Strings(sensitive)
+
+	return sensitive
 }
 
-// InheritFromParentContext set the client's headers to headers founded in the
-// provided context
+// RedactHeaders returns a copy of the headers with the values of the sensitive
+// ones replaced, so they can be safely logged.
+func (client *Client) RedactHeaders(headers http.Header) http.Header {
+	redacted := headers.Clone()
+	for header := range redacted {
+		if client.sensitive[http.CanonicalHeaderKey(header)] {
+			redacted[header] = []string{redactedValue}
+		}
+	}
+
+	return redacted
+}
+
+// injectHeaders gives the request its own copy of the headers, so changes made
+// to the request, or to the client while it is in flight, do not leak.
+func injectHeaders(request *http.Request, headers http.Header) {
+	request.Header = headers.Clone()
+}
+
+// InheritFromParentContext set the client's Authorization and X-trace-id
+// headers to the ones founded in the provided context
 func (client *Client) InheritFromParentContext(ctx *gin.Context) *Client {
+	return client.InheritHeadersFromParentContext(ctx, authorizationHeader, traceIDHeader)
+}
+
+// InheritHeadersFromParentContext copies each provided header from the request
+// of the provided context to the client, when present.
+func (client *Client) InheritHeadersFromParentContext(ctx *gin.Context, headers ...string) *Client {
+	clone := client.clone()
 	if ctx == nil || ctx.Request == nil {
-		return client
-	}
-	if len(ctx.Request.Header) == 0 {
-		return client
+		return clone
 	}
 
-	client.headers.Set(authorizationHeader, ctx.GetHeader(authorizationHeader))
-	return client
+	for _, header := range headers {
+		key := http.CanonicalHeaderKey(header)
+		if values := ctx.Request.Header[key]; len(values) > 0 {
+			clone.headers[key] = append([]string(nil), values...)
+		}
+	}
+	return clone
 }
 
-// SetHeader sets provided key - value in the headers
+// SetHeader sets provided key - value in the headers. It is safe to call
+// while the client is in use.
 func (client *Client) SetHeader(header, value string) {
-	client.headers.Set(header, value)
+	client.ApplyHeaders(func(headers http.Header) {
+		headers.Set(header, value)
+	})
 }
 
-// AddHeader adds provided key - value to the headers
+// SetHeaders sets each header of h in the headers, replacing its values and
+// keeping the rest of the headers. It is safe to call while the client is in
+// use.
+func (client *Client) SetHeaders(h http.Header) {
+	client.ApplyHeaders(func(headers http.Header) {
+		for key, values := range h {
+			headers[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+		}
+	})
+}
+
+// RemoveHeader removes the provided header, like an Authorization header
+// before an anonymous call. It is safe to call while the client is in use.
+func (client *Client) RemoveHeader(header string) {
+	client.ApplyHeaders(func(headers http.Header) {
+		headers.Del(header)
+	})
+}
+
+// ClearHeaders removes all the headers, including the default User-Agent. It
+// is safe to call while the client is in use.
+func (client *Client) ClearHeaders() {
+	client.ApplyHeaders(func(headers http.Header) {
+		for key := range headers {
+			delete(headers, key)
+		}
+	})
+}
+
+// ApplyHeaders mutates a copy of the client headers with apply, and then swaps
+// it in atomically, so calls in flight see either all the changes or none.
+func (client *Client) ApplyHeaders(apply func(headers http.Header)) {
+	client.headersMu.Lock()
+	defer client.headersMu.Unlock()
+
+	headers := client.headers.Clone()
+	apply(headers)
+	client.headers = headers
+}
+
+// snapshotHeaders returns the current client headers. They must be cloned
+// before being modified.
+func (client *Client) snapshotHeaders() http.Header {
+	client.headersMu.RLock()
+	defer client.headersMu.RUnlock()
+
+	return client.headers
+}
+
+// AddHeader adds provided key - value to the headers, keeping the values the
+// header already has. Use SetHeader to replace them. It is safe to call while
+// the client is in use.
 func (client *Client) AddHeader(header, value string) {
-	client.headers.Set(header, value)
+	client.ApplyHeaders(func(headers http.Header) {
+		headers.Add(header, value)
+	})
+}
+
+// HeaderNormalization configures how NormalizeHeaders handles multi-value
+// headers.
+type HeaderNormalization struct {
+	// Fold joins the values of a multi-value header into a single comma
+	// separated value. Set-Cookie is never folded, as cookie values may contain
+	// commas.
+	Fold bool
+	// DedupSetCookie keeps only the last Set-Cookie value of each cookie name.
+	DedupSetCookie bool
+}
+
+// NormalizeHeaders returns a copy of the response headers with canonical keys
+// and trimmed values, merging the values of keys that only differ in case.
+// Merged values follow the order of the keys, so the result is the same on
+// every run. Multi-value headers are handled according to config.
+func NormalizeHeaders(resp *http.Response, config HeaderNormalization) http.Header {
+	keys := make([]string, 0, len(resp.Header))
+	for key := range resp.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	normalized := http.Header{}
+	for _, key := range keys {
+		canonicalKey := http.CanonicalHeaderKey(key)
+		for _, value := range resp.Header[key] {
+			normalized[canonicalKey] = append(normalized[canonicalKey], strings.TrimSpace(value))
+		}
+	}
+
+	if config.DedupSetCookie {
+		normalized[setCookieHeader] = dedupSetCookie(normalized[setCookieHeader])
+		if len(normalized[setCookieHeader]) == 0 {
+			delete(normalized, setCookieHeader)
+		}
+	}
+
+	if config.Fold {
+		for key, values := range normalized {
+			if key != setCookieHeader && len(values) > 1 {
+				normalized[key] = []string{strings.Join(values, ", ")}
+			}
+		}
+	}
+
+	return normalized
+}
+
+func dedupSetCookie(cookies []string) []string {
+	lastByName := map[string]int{}
+	for i, cookie := range cookies {
+		lastByName[cookieName(cookie)] = i
+	}
+
+	deduped := make([]string, 0, len(lastByName))
+	for i, cookie := range cookies {
+		if lastByName[cookieName(cookie)] == i {
+			deduped = append(deduped, cookie)
+		}
+	}
+
+	return deduped
+}
+
+func cookieName(cookie string) string {
+	return strings.TrimSpace(strings.SplitN(cookie, "=", 2)[0])
 }
@@ -0,0 +1,382 @@
+package api_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+const acceptLanguageHeader = "Accept-Language"
+
+func TestWithLocale(t *testing.T) {
+	Convey("Given a list of language tags", t, func() {
+		tags := []string{"es-ES", "es", "en"}
+
+		Convey("When the client is initialized with the locale", func() {
+			client := api.MakeNewClient().WithLocale(tags...)
+
+			Convey("Then the Accept-Language header has decreasing quality values", func() {
+				So(client.GetHeaders().Get(acceptLanguageHeader), ShouldEqual, "es-ES, es;q=0.9, en;q=0.8")
+			})
+		})
+	})
+}
+
+func TestWithLocaleCache(t *testing.T) {
+	Convey("Given a cached client to a service whose responses vary on the locale", t, func() {
+		calls := new(int32)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(calls, 1)
+			w.Header().Set("Vary", acceptLanguageHeader)
+			w.Write([]byte(r.Header.Get(acceptLanguageHeader)))
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithCache()
+
+		Convey("When we make the same call with different locales", func() {
+			spanish := getBody(client.WithLocale("es"))
+			english := getBody(client.WithLocale("en"))
+			cachedSpanish := getBody(client.WithLocale("es"))
+
+			Convey("Then each locale has its own cache entry", func() {
+				So(atomic.LoadInt32(calls), ShouldEqual, 2)
+				So(spanish, ShouldEqual, "es")
+				So(english, ShouldEqual, "en")
+				So(cachedSpanish, ShouldEqual, "es")
+			})
+		})
+	})
+}
+
+func getBody(client *api.Client) string {
+	resp, err := client.GET(postsEndpoint, nil, nil)
+	So(err, ShouldBeNil)
+	body, err := ioutil.ReadAll(resp.Body)
+	So(err, ShouldBeNil)
+	return string(body)
+}
+
+func TestNormalizeHeaders(t *testing.T) {
+	Convey("Given a response with duplicate headers", t, func() {
+		resp := &http.Response{Header: http.Header{
+			"Cache-Control": []string{"no-cache"},
+			"cache-control": []string{" no-store "},
+			"Set-Cookie":    []string{"session=old; Path=/", "theme=dark", "session=new; Path=/"},
+			"Content-Type":  []string{"application/json"},
+		}}
+
+		Convey("When we normalize them without folding", func() {
+			headers := api.NormalizeHeaders(resp, api.HeaderNormalization{})
+
+			Convey("Then keys are canonical and values are merged in key order", func() {
+				So(headers["Cache-Control"], ShouldResemble, []string{"no-cache", "no-store"})
+				So(headers["Set-Cookie"], ShouldHaveLength, 3)
+				So(headers.Get("Content-Type"), ShouldEqual, "application/json")
+			})
+		})
+
+		Convey("When we normalize them folding and deduplicating cookies", func() {
+			headers := api.NormalizeHeaders(resp, api.HeaderNormalization{Fold: true, DedupSetCookie: true})
+
+			Convey("Then multi-value headers are folded but cookies are not", func() {
+				So(headers["Cache-Control"], ShouldResemble, []string{"no-cache, no-store"})
+				So(headers["Set-Cookie"], ShouldResemble, []string{"theme=dark", "session=new; Path=/"})
+			})
+		})
+	})
+}
+
+func TestWithSensitiveHeaders(t *testing.T) {
+	Convey("Given a client with a custom sensitive header and a logger", t, func() {
+		server, _ := newCountingServer()
+		defer server.Close()
+		logger := new(recordingLogger)
+		client := api.MakeNewClient().WithBasePath(server.URL).WithLogger(logger).
+			WithAuthHeader(testAuthBearer).WithAPIKey("secretKey").WithSensitiveHeaders("X-Secret")
+		client.SetHeader("X-Secret", "secretValue")
+		client.SetHeader("X-Public", "publicValue")
+
+		Convey("When we make a call", func() {
+			_, err := client.GET(postsEndpoint, nil, map[string][]string{"title": {"json-server"}})
+			So(err, ShouldBeNil)
+			output := logger.output()
+
+			Convey("Then the sensitive values are redacted from the logs", func() {
+				So(output, ShouldContainSubstring, "publicValue")
+				So(output, ShouldContainSubstring, "[REDACTED]")
+				So(output, ShouldNotContainSubstring, "secretValue")
+				So(output, ShouldNotContainSubstring, "testBearer")
+				So(output, ShouldNotContainSubstring, "secretKey")
+			})
+		})
+
+		Convey("When we redact the client headers", func() {
+			headers := client.RedactHeaders(client.GetHeaders())
+
+			Convey("Then the custom sensitive header is redacted", func() {
+				So(headers.Get("X-Secret"), ShouldEqual, "[REDACTED]")
+				So(headers.Get("X-Public"), ShouldEqual, "publicValue")
+				So(client.GetHeaders().Get("X-Secret"), ShouldEqual, "secretValue")
+			})
+		})
+	})
+}
+
+func TestInheritHeadersFromParentContext(t *testing.T) {
+	Convey("Given a parent gin.Context with an auth bearer and a trace id", t, func() {
+		context, bearer := getNewGinContextWithAuthBearer()
+		context.Request.Header.Set("X-trace-id", "trace-1")
+		context.Request.Header.Set("X-Tenant", "truman")
+		context.Request.Header.Set("X-Ignored", "capote")
+
+		Convey("When the client inherits from the context", func() {
+			client := api.MakeNewClient().InheritFromParentContext(context)
+
+			Convey("Then the auth header and the trace id are propagated", func() {
+				So(client.GetHeaders().Get(authHeader), ShouldEqual, bearer)
+				So(client.GetHeaders().Get("X-trace-id"), ShouldEqual, "trace-1")
+				So(client.GetHeaders().Get("X-Tenant"), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the client inherits an allowlist of headers", func() {
+			client := api.MakeNewClient().InheritHeadersFromParentContext(context, "X-Tenant", "X-Missing")
+
+			Convey("Then only the present allowed headers are copied", func() {
+				So(client.GetHeaders().Get("X-Tenant"), ShouldEqual, "truman")
+				So(client.GetHeaders().Get(authHeader), ShouldBeEmpty)
+				So(client.GetHeaders().Get("X-Ignored"), ShouldBeEmpty)
+				So(client.GetHeaders(), ShouldNotContainKey, "X-Missing")
+			})
+		})
+	})
+}
+
+func TestWithUserAgent(t *testing.T) {
+	Convey("Given a service that echoes the User-Agent", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.UserAgent()))
+		}))
+		defer server.Close()
+
+		Convey("When the client has a custom user agent", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithUserAgent("truman/1.0")
+
+			Convey("Then the user agent is set and reaches the service", func() {
+				So(client.GetHeaders().Get("User-Agent"), ShouldEqual, "truman/1.0")
+				So(getBody(client), ShouldEqual, "truman/1.0")
+			})
+		})
+
+		Convey("When the client has the default user agent", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL)
+
+			Convey("Then the library user agent reaches the service", func() {
+				So(client.GetHeaders().Get("User-Agent"), ShouldEqual, "BlackBeard/"+api.Version)
+				So(getBody(client), ShouldEqual, "BlackBeard/"+api.Version)
+			})
+		})
+	})
+}
+
+func TestApplyHeaders(t *testing.T) {
+	Convey("Given a service that records a pair of headers set together", t, func() {
+		var mismatches int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Tenant") != r.Header.Get("X-Tenant-Check") {
+				atomic.AddInt32(&mismatches, 1)
+			}
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we apply header changes while calls are in flight", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				tenant := strconv.Itoa(i)
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					client.ApplyHeaders(func(headers http.Header) {
+						headers.Set("X-Tenant", tenant)
+						headers.Set("X-Tenant-Check", tenant)
+					})
+				}()
+				go func() {
+					defer wg.Done()
+					resp, err := client.GET(postsEndpoint, nil)
+					if err == nil {
+						resp.Body.Close()
+					}
+				}()
+			}
+			wg.Wait()
+
+			Convey("Then every call sees all the changes of an update or none", func() {
+				So(atomic.LoadInt32(&mismatches), ShouldEqual, 0)
+				headers := client.GetHeaders()
+				So(headers.Get("X-Tenant"), ShouldEqual, headers.Get("X-Tenant-Check"))
+			})
+		})
+	})
+}
+
+func TestAddHeader(t *testing.T) {
+	Convey("Given a service that records the values of a header", t, func() {
+		var accept []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accept = r.Header.Values("Accept")
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we add two values for the header", func() {
+			client.AddHeader("Accept", "application/json")
+			client.AddHeader("Accept", "application/xml")
+			checkResponseIsValid(client.GET(postsEndpoint, nil))
+
+			Convey("Then both values survive", func() {
+				So(accept, ShouldResemble, []string{"application/json", "application/xml"})
+			})
+		})
+
+		Convey("When we set the header after adding a value", func() {
+			client.AddHeader("Accept", "application/json")
+			client.SetHeader("Accept", "application/xml")
+			checkResponseIsValid(client.GET(postsEndpoint, nil))
+
+			Convey("Then only the set value remains", func() {
+				So(accept, ShouldResemble, []string{"application/xml"})
+			})
+		})
+	})
+}
+
+func TestBulkHeaders(t *testing.T) {
+	Convey("Given a service that records the headers and a client with some of them", t, func() {
+		var received http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithAuthHeader(testAuthBearer).WithTraceID("truman")
+
+		Convey("When we set several headers at once", func() {
+			client.SetHeaders(http.Header{"x-trace-id": {"capote"}, "X-Tenant": {"novels"}})
+			checkResponseIsValid(client.GET(postsEndpoint, nil))
+
+			Convey("Then they are merged with the existing ones", func() {
+				So(received.Get(authHeader), ShouldEqual, testAuthBearer)
+				So(received.Values("X-Trace-Id"), ShouldResemble, []string{"capote"})
+				So(received.Get("X-Tenant"), ShouldEqual, "novels")
+			})
+		})
+
+		Convey("When we remove the Authorization header", func() {
+			client.RemoveHeader(authHeader)
+			checkResponseIsValid(client.GET(postsEndpoint, nil))
+
+			Convey("Then the call is anonymous and keeps the rest of the headers", func() {
+				So(received.Get(authHeader), ShouldBeEmpty)
+				So(received.Get("X-Trace-Id"), ShouldEqual, "truman")
+			})
+		})
+
+		Convey("When we clear the headers", func() {
+			client.ClearHeaders()
+			checkResponseIsValid(client.GET(postsEndpoint, nil))
+
+			Convey("Then none of them is sent", func() {
+				So(client.GetHeaders(), ShouldBeEmpty)
+				So(received.Get(authHeader), ShouldBeEmpty)
+				So(received.Get("X-Trace-Id"), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When we derive a client with other headers", func() {
+			derived := client.WithHeaders(http.Header{"x-tenant": {"novels"}})
+			checkResponseIsValid(derived.GET(postsEndpoint, nil))
+
+			Convey("Then they replace all the headers of the derived client only", func() {
+				So(derived.GetHeaders(), ShouldResemble, http.Header{"X-Tenant": {"novels"}})
+				So(received.Get(authHeader), ShouldBeEmpty)
+				So(received.Get("X-Tenant"), ShouldEqual, "novels")
+				So(client.GetHeaders().Get(authHeader), ShouldEqual, testAuthBearer)
+			})
+		})
+	})
+}
+
+func TestGetHeaders(t *testing.T) {
+	Convey("Given a client with an Authorization header", t, func() {
+		client := api.MakeNewClient().WithAuthHeader(testAuthBearer)
+		derived := client.WithTraceID("truman")
+
+		Convey("When we change the headers it returns", func() {
+			headers := client.GetHeaders()
+			headers.Del(authHeader)
+			headers.Set("X-Tenant", "novels")
+
+			Convey("Then the client and its clones are untouched", func() {
+				So(client.GetHeaders().Get(authHeader), ShouldEqual, testAuthBearer)
+				So(client.GetHeaders().Get("X-Tenant"), ShouldBeEmpty)
+				So(derived.GetHeaders().Get(authHeader), ShouldEqual, testAuthBearer)
+			})
+		})
+	})
+}
+
+// TestConcurrentHeaderMutation is meant to be run with go test -race.
+func TestConcurrentHeaderMutation(t *testing.T) {
+	Convey("Given a client shared by several goroutines", t, func() {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When they mutate its headers, derive clients and call at the same time", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				value := strconv.Itoa(i)
+				wg.Add(4)
+				go func() {
+					defer wg.Done()
+					client.SetHeader("X-Request", value)
+				}()
+				go func() {
+					defer wg.Done()
+					client.AddHeader("X-Extra", value)
+				}()
+				go func() {
+					defer wg.Done()
+					derived := client.WithAuthHeader("Bearer " + value).WithTraceID(value)
+					_ = derived.GetHeaders().Get(authHeader)
+				}()
+				go func() {
+					defer wg.Done()
+					resp, err := client.GET(postsEndpoint, nil)
+					if err == nil {
+						resp.Body.Close()
+					}
+				}()
+			}
+			wg.Wait()
+
+			Convey("Then every call is made and the headers stay consistent", func() {
+				So(atomic.LoadInt32(&calls), ShouldEqual, 50)
+				So(client.GetHeaders().Get("X-Request"), ShouldNotBeEmpty)
+				So(client.GetHeaders().Get(authHeader), ShouldBeEmpty)
+			})
+		})
+	})
+}
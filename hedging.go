@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WithHedging sends a second identical GET call when the first one has not
+// answered after delay, and returns the response of whichever answers first.
+// The other call is cancelled. It cuts the tail latency of idempotent reads at
+// the cost of some extra load on the service.
+func (client *Client) WithHedging(delay time.Duration) *Client {
+	clone := client.clone()
+	clone.hedging = delay
+	return clone
+}
+
+func (client *Client) shouldHedge(request *http.Request) bool {
+	return client.hedging > 0 && request.Method == http.MethodGet && request.Body == nil
+}
+
+type hedgedResult struct {
+	response *http.Response
+	err      error
+	attempt  int
+}
+
+func (client *Client) doHedged(request *http.Request) (*http.Response, error) {
+	if !client.shouldHedge(request) {
+		return client.do(request)
+	}
+
+	results := make(chan hedgedResult, 2)
+	cancels := []context.CancelFunc{client.launchAttempt(request, 0, results)}
+
+	timer := time.NewTimer(client.hedging)
+	defer timer.Stop()
+
+	var winner hedgedResult
+	select {
+	case winner = <-results:
+	case <-timer.C:
+		client.logger.Debugf("Hedging [%s] %s after %v\n", request.Method, client.redactURL(request.URL), client.hedging)
+		cancels = append(cancels, client.launchAttempt(request, 1, results))
+		winner = <-results
+	}
+
+	pending := len(cancels) - 1
+	if winner.err != nil && pending > 0 {
+		cancels[winner.attempt]()
+		winner = <-results
+		pending--
+	}
+
+	for attempt, cancel := range cancels {
+		if attempt != winner.attempt {
+			cancel()
+		}
+	}
+	if pending > 0 {
+		go discardAttempt(results)
+	}
+
+	if winner.err != nil {
+		cancels[winner.attempt]()
+		return nil, winner.err
+	}
+
+	winner.response.Body = &cancelOnClose{ReadCloser: winner.response.Body, cancel: cancels[winner.attempt]}
+	return winner.response, nil
+}
+
+// launchAttempt sends a copy of the request on its own context, and returns
+// the function that cancels it.
+func (client *Client) launchAttempt(request *http.Request, attempt int, results chan<- hedgedResult) context.CancelFunc {
+	ctx, cancel := context.WithCancel(request.Context())
+	hedged := request.Clone(ctx)
+
+	go func() {
+		response, err := client.do(hedged)
+		results <- hedgedResult{response: response, err: err, attempt: attempt}
+	}()
+
+	return cancel
+}
+
+// discardAttempt releases the response of the losing attempt, once the
+// cancelled call returns.
+func discardAttempt(results <-chan hedgedResult) {
+	loser := <-results
+	closeBody(loser.response)
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (body *cancelOnClose) Close() error {
+	err := body.ReadCloser.Close()
+	body.cancel()
+	return err
+}
@@ -0,0 +1,70 @@
+package api_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func TestWithHedging(t *testing.T) {
+	Convey("Given a service whose first answer is slow and the next ones fast", t, func() {
+		calls := new(int32)
+		cancelled := make(chan struct{}, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(calls, 1) == 1 {
+				select {
+				case <-time.After(2 * time.Second):
+				case <-r.Context().Done():
+					cancelled <- struct{}{}
+				}
+				w.Write([]byte("slow"))
+				return
+			}
+			w.Write([]byte("fast"))
+		}))
+		defer server.Close()
+
+		Convey("When we make a hedged GET call", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithHedging(50 * time.Millisecond)
+			start := time.Now()
+			resp, err := client.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+			body, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			Convey("Then the faster response wins", func() {
+				So(err, ShouldBeNil)
+				So(string(body), ShouldEqual, "fast")
+				So(time.Since(start), ShouldBeLessThan, 500*time.Millisecond)
+				So(atomic.LoadInt32(calls), ShouldEqual, 2)
+			})
+
+			Convey("Then the slower call is cancelled right away", func() {
+				select {
+				case <-cancelled:
+				case <-time.After(time.Second):
+					So("the slower call was not cancelled", ShouldBeEmpty)
+				}
+			})
+		})
+
+		Convey("When we make a hedged POST call", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithHedging(50 * time.Millisecond)
+			resp, err := client.POST(postsEndpoint, testPost)
+			So(err, ShouldBeNil)
+			body, _ := ioutil.ReadAll(resp.Body)
+
+			Convey("Then it is not hedged", func() {
+				So(string(body), ShouldEqual, "slow")
+				So(atomic.LoadInt32(calls), ShouldEqual, 1)
+			})
+		})
+	})
+}
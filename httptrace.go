@@ -0,0 +1,89 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+)
+
+// WithHTTPTrace enables connection level tracing of the calls: DNS lookups,
+// dials and connection reuse are logged at debug level, and DNS failures at
+// error level. Calls whose host can't be resolved fail with a
+// *DNSResolutionError, matched by ErrDNSResolution, instead of a generic
+// transport error.
+func (client *Client) WithHTTPTrace() *Client {
+	clone := client.clone()
+	clone.httpTrace = true
+	return clone
+}
+
+func (client *Client) withHTTPTrace(request *http.Request) (*http.Request, *connTrace) {
+	if !client.httpTrace {
+		return request, nil
+	}
+
+	trace := &connTrace{logger: client.logger}
+	ctx := httptrace.WithClientTrace(request.Context(), trace.clientTrace())
+	return request.WithContext(ctx), trace
+}
+
+// connTrace records the connection events of a call. Hedged attempts share
+// it, so it is guarded by a mutex.
+type connTrace struct {
+	logger Logger
+	mu     sync.Mutex
+	host   string
+	dnsErr error
+}
+
+func (trace *connTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			trace.mu.Lock()
+			trace.host = info.Host
+			trace.mu.Unlock()
+			trace.logger.Debugf("DNS lookup of %s\n", info.Host)
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			trace.mu.Lock()
+			host := trace.host
+			if info.Err != nil {
+				trace.dnsErr = info.Err
+			}
+			trace.mu.Unlock()
+
+			if info.Err != nil {
+				trace.logger.Errorf("DNS lookup of %s failed: %v\n", host, info.Err)
+				return
+			}
+			trace.logger.Debugf("DNS lookup of %s resolved to %v\n", host, info.Addrs)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err != nil {
+				trace.logger.Debugf("Dial %s %s failed: %v\n", network, addr, err)
+				return
+			}
+			trace.logger.Debugf("Dialed %s %s\n", network, addr)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			trace.logger.Debugf("Got connection to %v, reused: %v\n", info.Conn.RemoteAddr(), info.Reused)
+		},
+	}
+}
+
+// wrap returns a *DNSResolutionError if the call failed to resolve its host,
+// or err as is otherwise.
+func (trace *connTrace) wrap(err error) error {
+	if trace == nil {
+		return err
+	}
+
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+
+	if trace.dnsErr == nil {
+		return err
+	}
+
+	return &DNSResolutionError{Host: trace.host, Err: err}
+}
@@ -0,0 +1,41 @@
+package api_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+const unresolvableBasePath = "http://blackbeard.invalid"
+
+func TestWithHTTPTrace(t *testing.T) {
+	Convey("Given an unresolvable host", t, func() {
+		Convey("When we call it with HTTP trace enabled", func() {
+			client := api.MakeNewClient().WithBasePath(unresolvableBasePath).WithHTTPTrace()
+			_, err := client.GET(postsEndpoint, nil)
+
+			Convey("Then the error is a DNS resolution error wrapping the resolver one", func() {
+				So(errors.Is(err, api.ErrDNSResolution), ShouldBeTrue)
+				var dnsErr *api.DNSResolutionError
+				So(errors.As(err, &dnsErr), ShouldBeTrue)
+				So(dnsErr.Host, ShouldEqual, "blackbeard.invalid")
+				var resolverErr *net.DNSError
+				So(errors.As(err, &resolverErr), ShouldBeTrue)
+			})
+		})
+
+		Convey("When we call it without HTTP trace", func() {
+			client := api.MakeNewClient().WithBasePath(unresolvableBasePath)
+			_, err := client.GET(postsEndpoint, nil)
+
+			Convey("Then the error is a generic transport error", func() {
+				So(err, ShouldNotBeNil)
+				So(errors.Is(err, api.ErrDNSResolution), ShouldBeFalse)
+			})
+		})
+	})
+}
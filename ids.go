@@ -0,0 +1,53 @@
+package api
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// IDGenerator produces the ids used by the client, like generated trace ids.
+type IDGenerator interface {
+	NewID() string
+}
+
+// IDGeneratorFunc adapts an ordinary function to the IDGenerator interface.
+type IDGeneratorFunc func() string
+
+// NewID calls f().
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+// UUIDGenerator generates random (version 4) UUIDs. It is the default
+// generator of the client.
+type UUIDGenerator struct{}
+
+// NewID returns a new random UUID.
+func (UUIDGenerator) NewID() string {
+	uuid := make([]byte, 16)
+	_, err := rand.Read(uuid)
+	if err != nil {
+		panic(err)
+	}
+
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
+
+// WithIDGenerator sets the generator of the ids produced by the client.
+func (client *Client) WithIDGenerator(generator IDGenerator) *Client {
+	clone := client.clone()
+	clone.ids = generator
+	return clone
+}
+
+// NewID returns a new id from the client generator.
+func (client *Client) NewID() string {
+	return client.ids.NewID()
+}
+
+// WithGeneratedTraceID sets the X-trace-id header to a new generated id.
+func (client *Client) WithGeneratedTraceID() *Client {
+	return client.WithTraceID(client.NewID())
+}
@@ -0,0 +1,50 @@
+package api_test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+const traceIDHeader = "X-trace-id"
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestWithIDGenerator(t *testing.T) {
+	Convey("Given a client with a deterministic id generator", t, func() {
+		sequence := 0
+		client := api.MakeNewClient().WithIDGenerator(api.IDGeneratorFunc(func() string {
+			sequence++
+			return fmt.Sprintf("id-%d", sequence)
+		}))
+
+		Convey("When the client generates ids", func() {
+			first := client.NewID()
+			client = client.WithGeneratedTraceID()
+
+			Convey("Then the ids are predictable", func() {
+				So(first, ShouldEqual, "id-1")
+				So(client.GetHeaders().Get(traceIDHeader), ShouldEqual, "id-2")
+			})
+		})
+	})
+
+	Convey("Given a client with the default id generator", t, func() {
+		client := api.MakeNewClient()
+
+		Convey("When the client generates ids", func() {
+			first := client.NewID()
+			second := client.NewID()
+
+			Convey("Then they are different random UUIDs", func() {
+				So(uuidPattern.MatchString(first), ShouldBeTrue)
+				So(uuidPattern.MatchString(second), ShouldBeTrue)
+				So(first, ShouldNotEqual, second)
+			})
+		})
+	})
+}
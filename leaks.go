@@ -0,0 +1,100 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// WithBodyLeakDetection tracks every response body returned by the client, so
+// Close reports the ones that were never closed. It is meant for tests, to
+// catch callers that forget to close resp.Body.
+func (client *Client) WithBodyLeakDetection() *Client {
+	clone := client.clone()
+	clone.bodyLeaks = &leakDetector{open: map[*trackedBody]string{}}
+	return clone
+}
+
+func (client *Client) trackBody(response *http.Response) {
+	if client.bodyLeaks == nil || response.Body == nil {
+		return
+	}
+
+	call := fmt.Sprintf("[%s] %s", response.Request.Method, client.redactURL(response.Request.URL))
+	response.Body = client.bodyLeaks.track(response.Body, call)
+}
+
+func (client *Client) checkBodyLeaks() error {
+	if client.bodyLeaks == nil {
+		return nil
+	}
+
+	leaks := client.bodyLeaks.leaks()
+	if len(leaks) == 0 {
+		return nil
+	}
+
+	return &BodyLeakError{Calls: leaks}
+}
+
+type leakDetector struct {
+	sync.Mutex
+	open map[*trackedBody]string
+}
+
+func (detector *leakDetector) track(body io.ReadCloser, call string) io.ReadCloser {
+	tracked := &trackedBody{ReadCloser: body, detector: detector}
+
+	detector.Lock()
+	defer detector.Unlock()
+	detector.open[tracked] = call
+	return tracked
+}
+
+func (detector *leakDetector) release(body *trackedBody) {
+	detector.Lock()
+	defer detector.Unlock()
+	delete(detector.open, body)
+}
+
+func (detector *leakDetector) leaks() []string {
+	detector.Lock()
+	defer detector.Unlock()
+
+	calls := make([]string, 0, len(detector.open))
+	for _, call := range detector.open {
+		calls = append(calls, call)
+	}
+	sort.Strings(calls)
+
+	return calls
+}
+
+type trackedBody struct {
+	io.ReadCloser
+	detector *leakDetector
+}
+
+func (body *trackedBody) Close() error {
+	body.detector.release(body)
+	return body.ReadCloser.Close()
+}
+
+// BodyLeakError is returned by Close when body leak detection is enabled and
+// some response bodies were not closed. Calls lists the leaked calls.
+type BodyLeakError struct {
+	Calls []string
+}
+
+func (e *BodyLeakError) Error() string {
+	return fmt.Sprintf("%d response bodies were not closed: %s", len(e.Calls), strings.Join(e.Calls, ", "))
+}
+
+// IsBodyLeakError checks if the error is a BodyLeakError error.
+func IsBodyLeakError(err error) bool {
+	_, ok := err.(*BodyLeakError)
+	return ok
+}
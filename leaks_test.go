@@ -0,0 +1,43 @@
+package api_test
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func TestWithBodyLeakDetection(t *testing.T) {
+	Convey("Given a client with body leak detection", t, func() {
+		server, _ := newCountingServer()
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithBodyLeakDetection()
+
+		Convey("When a response body is leaked", func() {
+			closed, err := client.GET(postsEndpoint, nil, nil)
+			So(err, ShouldBeNil)
+			closed.Body.Close()
+			_, err = client.GET(postsEndpoint+"/1", nil, nil)
+			So(err, ShouldBeNil)
+
+			err = client.Close()
+
+			Convey("Then Close reports the leaked call", func() {
+				So(api.IsBodyLeakError(err), ShouldBeTrue)
+				So(err.(*api.BodyLeakError).Calls, ShouldHaveLength, 1)
+				So(err.(*api.BodyLeakError).Calls[0], ShouldContainSubstring, postsEndpoint+"/1")
+			})
+		})
+
+		Convey("When every response body is closed", func() {
+			resp, err := client.GET(postsEndpoint, nil, nil)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then Close reports nothing", func() {
+				So(client.Close(), ShouldBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"io"
+	"net/http"
+)
+
+// WithMaxResponseSize limits the size, in bytes, of the response bodies.
+// Reading past the limit fails with ErrResponseTooLarge, so an unbounded body
+// can't exhaust the memory of the client. Zero, the default, means unlimited.
+func (client *Client) WithMaxResponseSize(size int64) *Client {
+	clone := client.clone()
+	clone.maxBody = size
+	return clone
+}
+
+func (client *Client) limitBody(response *http.Response) {
+	if client.maxBody <= 0 || response.Body == nil || response.Body == http.NoBody {
+		return
+	}
+
+	response.Body = &limitedBody{ReadCloser: response.Body, remaining: client.maxBody}
+}
+
+// limitedBody reads up to remaining bytes, and fails with ErrResponseTooLarge
+// if the body has more.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (body *limitedBody) Read(p []byte) (int, error) {
+	if int64(len(p)) > body.remaining+1 {
+		p = p[:body.remaining+1]
+	}
+
+	n, err := body.ReadCloser.Read(p)
+	if int64(n) > body.remaining {
+		n = int(body.remaining)
+		body.remaining = 0
+		return n, ErrResponseTooLarge
+	}
+
+	body.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,53 @@
+package api_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func TestWithMaxResponseSize(t *testing.T) {
+	Convey("Given a service answering a 2KB JSON body", t, func() {
+		body := fmt.Sprintf(`{"title":"%v"}`, strings.Repeat("a", 2048-12))
+		server := newStaticServer("application/json", body)
+		defer server.Close()
+
+		Convey("When a client limited to 1KB reads it", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithMaxResponseSize(1024)
+			resp, err := client.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+			_, err = api.Body2Interface(resp)
+
+			Convey("Then it fails with ErrResponseTooLarge", func() {
+				So(errors.Is(err, api.ErrResponseTooLarge), ShouldBeTrue)
+			})
+		})
+
+		Convey("When a client limited to the body size reads it", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithMaxResponseSize(int64(len(body)))
+			resp, err := client.GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+			data, err := api.Body2Interface(resp)
+
+			Convey("Then it is parsed", func() {
+				So(err, ShouldBeNil)
+				So(data, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When a client without limit reads it", func() {
+			resp, err := api.MakeNewClient().WithBasePath(server.URL).GET(postsEndpoint, nil)
+			So(err, ShouldBeNil)
+			_, err = api.Body2Interface(resp)
+
+			Convey("Then it is parsed", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
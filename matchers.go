@@ -0,0 +1,130 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// The matchers below follow the goconvey assertion signature, so they can be
+// used straight with So:
+//
+//	So(resp, api.ShouldHaveStatus, http.StatusOK)
+//	So(resp, api.ShouldHaveHeader, "Content-Type", "application/json")
+//	So(resp, api.ShouldDecodeTo, &post, expectedPost)
+//
+// As with any goconvey assertion, they return an empty string on success and
+// the failure message otherwise.
+
+// ShouldHaveStatus asserts that the response has the expected status code.
+func ShouldHaveStatus(actual interface{}, expected ...interface{}) string {
+	resp, message := matcherResponse(actual, expected, 1)
+	if message != "" {
+		return message
+	}
+
+	status, ok := expected[0].(int)
+	if !ok {
+		return fmt.Sprintf("Expected status must be an int (was %T)!", expected[0])
+	}
+
+	if resp.StatusCode != status {
+		return fmt.Sprintf("Expected status: %d\nActual:          %d", status, resp.StatusCode)
+	}
+
+	return ""
+}
+
+// ShouldHaveHeader asserts that the response has the expected header. When a
+// value is also provided, the header must have that value.
+func ShouldHaveHeader(actual interface{}, expected ...interface{}) string {
+	if len(expected) != 1 && len(expected) != 2 {
+		return fmt.Sprintf("This assertion requires 1 or 2 comparison values (you provided %d).", len(expected))
+	}
+
+	if len(expected) == 2 {
+		return shouldHaveHeaderValue(actual, expected...)
+	}
+
+	resp, message := matcherResponse(actual, expected, 1)
+	if message != "" {
+		return message
+	}
+
+	name, ok := expected[0].(string)
+	if !ok {
+		return fmt.Sprintf("Expected header name must be a string (was %T)!", expected[0])
+	}
+
+	if _, found := resp.Header[http.CanonicalHeaderKey(name)]; !found {
+		return fmt.Sprintf("Expected header %q, but it was not present", name)
+	}
+
+	return ""
+}
+
+func shouldHaveHeaderValue(actual interface{}, expected ...interface{}) string {
+	resp, message := matcherResponse(actual, expected, 2)
+	if message != "" {
+		return message
+	}
+
+	name, okName := expected[0].(string)
+	value, okValue := expected[1].(string)
+	if !okName || !okValue {
+		return "Expected header name and value must be strings!"
+	}
+
+	if actualValue := resp.Header.Get(name); actualValue != value {
+		return fmt.Sprintf("Expected header %q: %q\nActual:             %q", name, value, actualValue)
+	}
+
+	return ""
+}
+
+// ShouldDecodeTo asserts that the response body can be parsed, as
+// ParseResponseTo does, to the receiver. When an expected value is also
+// provided, the parsed receiver must be deeply equal to it.
+func ShouldDecodeTo(actual interface{}, expected ...interface{}) string {
+	if len(expected) != 1 && len(expected) != 2 {
+		return fmt.Sprintf("This assertion requires 1 or 2 comparison values (you provided %d).", len(expected))
+	}
+
+	resp, message := matcherResponse(actual, expected[:1], 1)
+	if message != "" {
+		return message
+	}
+
+	receiver := expected[0]
+	if !isAPointer(receiver) {
+		return fmt.Sprintf("The receiver must be a pointer (was %T)!", receiver)
+	}
+
+	if err := ParseResponseTo(resp, receiver); err != nil {
+		return fmt.Sprintf("Expected the response to decode to %T, but it failed: %v", receiver, err)
+	}
+
+	if len(expected) == 1 {
+		return ""
+	}
+
+	decoded := reflect.ValueOf(receiver).Elem().Interface()
+	if !reflect.DeepEqual(decoded, expected[1]) {
+		return fmt.Sprintf("Expected: %+v\nActual:   %+v", expected[1], decoded)
+	}
+
+	return ""
+}
+
+func matcherResponse(actual interface{}, expected []interface{}, want int) (*http.Response, string) {
+	if len(expected) != want {
+		return nil, fmt.Sprintf("This assertion requires exactly %d comparison values (you provided %d).", want, len(expected))
+	}
+
+	resp, ok := actual.(*http.Response)
+	if !ok || resp == nil {
+		return nil, fmt.Sprintf("This assertion requires a non nil *http.Response (was %T)!", actual)
+	}
+
+	return resp, ""
+}
@@ -0,0 +1,78 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func TestMatchers(t *testing.T) {
+	Convey("Given a response with a JSON post", t, func() {
+		server := newStaticServer("application/json", `{"title":"Desayuno con diamantes","author":"Truman Capote"}`)
+		defer server.Close()
+		resp, err := api.MakeNewClient().WithBasePath(server.URL).GET(postsEndpoint, nil)
+		So(err, ShouldBeNil)
+
+		Convey("When we assert on its status", func() {
+			Convey("Then ShouldHaveStatus only passes for the actual status", func() {
+				So(resp, api.ShouldHaveStatus, http.StatusOK)
+				So(api.ShouldHaveStatus(resp, http.StatusNotFound), ShouldNotBeBlank)
+				So(api.ShouldHaveStatus(resp, "200"), ShouldNotBeBlank)
+				So(api.ShouldHaveStatus(nil, http.StatusOK), ShouldNotBeBlank)
+			})
+		})
+
+		Convey("When we assert on its headers", func() {
+			Convey("Then ShouldHaveHeader checks the presence and the value", func() {
+				So(resp, api.ShouldHaveHeader, "content-type")
+				So(resp, api.ShouldHaveHeader, "Content-Type", "application/json")
+				So(api.ShouldHaveHeader(resp, "X-Missing"), ShouldNotBeBlank)
+				So(api.ShouldHaveHeader(resp, "Content-Type", "text/plain"), ShouldNotBeBlank)
+				So(api.ShouldHaveHeader(resp), ShouldNotBeBlank)
+			})
+		})
+
+		Convey("When we assert on its body", func() {
+			var post map[string]interface{}
+
+			Convey("Then ShouldDecodeTo decodes it and compares with the expected value", func() {
+				So(resp, api.ShouldDecodeTo, &post, map[string]interface{}{
+					"title":  "Desayuno con diamantes",
+					"author": "Truman Capote",
+				})
+				So(post["author"], ShouldEqual, "Truman Capote")
+			})
+
+			Convey("Then ShouldDecodeTo fails for a different value", func() {
+				So(api.ShouldDecodeTo(resp, &post, map[string]interface{}{"title": "A sangre fría"}), ShouldNotBeBlank)
+			})
+
+			Convey("Then ShouldDecodeTo fails for a non pointer receiver", func() {
+				So(api.ShouldDecodeTo(resp, post), ShouldNotBeBlank)
+			})
+		})
+	})
+
+	Convey("Given a response with an error status", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+		resp, err := api.MakeNewClient().WithBasePath(server.URL).GET(postsEndpoint, nil)
+		So(err, ShouldBeNil)
+
+		Convey("When we assert it decodes", func() {
+			var post map[string]interface{}
+			message := api.ShouldDecodeTo(resp, &post)
+
+			Convey("Then the assertion fails", func() {
+				So(resp, api.ShouldHaveStatus, http.StatusNotFound)
+				So(message, ShouldNotBeBlank)
+			})
+		})
+	})
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+	"time"
+)
+
+// MetricsObserver receives a measure of every call made by the client. It is
+// the integration point for metrics systems like Prometheus, whose histograms
+// and counters can be fed from it.
+type MetricsObserver interface {
+	// ObserveRequest is called when a call completes. status is 0 when the
+	// call failed without a response.
+	ObserveRequest(method, service string, status int, duration time.Duration)
+}
+
+// WithMetrics sets the observer that receives the metrics of every call.
+func (client *Client) WithMetrics(observer MetricsObserver) *Client {
+	clone := client.clone()
+	clone.metrics = observer
+	return clone
+}
+
+func (client *Client) observe(method string, response *http.Response, duration time.Duration) {
+	if client.metrics == nil {
+		return
+	}
+
+	status := 0
+	if response != nil {
+		status = response.StatusCode
+	}
+
+	client.metrics.ObserveRequest(method, client.service, status, duration)
+}
@@ -0,0 +1,62 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+type observation struct {
+	method   string
+	service  string
+	status   int
+	duration time.Duration
+}
+
+type fakeObserver struct {
+	observations []observation
+}
+
+func (observer *fakeObserver) ObserveRequest(method, service string, status int, duration time.Duration) {
+	observer.observations = append(observer.observations, observation{method, service, status, duration})
+}
+
+func TestWithMetrics(t *testing.T) {
+	Convey("Given a client with a metrics observer", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+		observer := new(fakeObserver)
+		client := api.MakeNewClient().WithBasePath(server.URL).ToService(testTargetService).WithMetrics(observer)
+
+		Convey("When we make a call", func() {
+			_, err := client.DELETE(postsEndpoint+"/1", nil, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then the observer receives the call labels", func() {
+				So(observer.observations, ShouldHaveLength, 1)
+				So(observer.observations[0].method, ShouldEqual, http.MethodDelete)
+				So(observer.observations[0].service, ShouldEqual, testTargetService)
+				So(observer.observations[0].status, ShouldEqual, http.StatusNotFound)
+				So(observer.observations[0].duration, ShouldBeGreaterThan, 0)
+			})
+		})
+
+		Convey("When a call fails", func() {
+			server.Close()
+			_, err := client.GET(postsEndpoint, nil, nil)
+
+			Convey("Then the observer receives a zero status", func() {
+				So(err, ShouldNotBeNil)
+				So(observer.observations, ShouldHaveLength, 1)
+				So(observer.observations[0].status, ShouldEqual, 0)
+			})
+		})
+	})
+}
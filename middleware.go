@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"time"
+)
+
+// Next sends the request to the next interceptor of the chain, or to the
+// service once the chain is exhausted.
+type Next func(*http.Request) (*http.Response, error)
+
+// Interceptor inspects or modifies an outgoing request and its response. It
+// must call next to continue with the call, unless it wants to short-circuit
+// it returning its own response or error.
+type Interceptor func(request *http.Request, next Next) (*http.Response, error)
+
+// Use appends interceptors to the client chain. Interceptors wrap the calls in
+// the order they were added, so the first one sees the request first and the
+// response last.
+func (client *Client) Use(interceptors ...Interceptor) *Client {
+	clone := client.clone()
+	clone.middleware = append(clone.middleware, interceptors...)
+	return clone
+}
+
+func (client *Client) chain(final Next) Next {
+	next := final
+	for i := len(client.middleware) - 1; i >= 0; i-- {
+		next = wrapInterceptor(client.middleware[i], next)
+	}
+
+	return next
+}
+
+func wrapInterceptor(interceptor Interceptor, next Next) Next {
+	return func(request *http.Request) (*http.Response, error) {
+		return interceptor(request, next)
+	}
+}
+
+// WithOnRequest sets a hook called with every request right before it is sent.
+func (client *Client) WithOnRequest(hook func(*http.Request)) *Client {
+	clone := client.clone()
+	clone.onRequest = hook
+	return clone
+}
+
+// WithOnResponse sets a hook called with every response received from the
+// service and the measured round-trip duration. It is not called for cached
+// responses nor for failed calls.
+func (client *Client) WithOnResponse(hook func(*http.Response, time.Duration)) *Client {
+	clone := client.clone()
+	clone.onResponse = hook
+	return clone
+}
+
+func (client *Client) notifyRequest(request *http.Request) {
+	if client.onRequest != nil {
+		client.onRequest(request)
+	}
+}
+
+func (client *Client) notifyResponse(response *http.Response, duration time.Duration) {
+	if client.onResponse != nil {
+		client.onResponse(response, duration)
+	}
+}
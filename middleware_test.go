@@ -0,0 +1,97 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func TestUse(t *testing.T) {
+	Convey("Given a client with interceptors", t, func() {
+		var receivedSignature string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			receivedSignature = r.Header.Get("X-Signature")
+		}))
+		defer server.Close()
+
+		order := []string{}
+		client := api.MakeNewClient().WithBasePath(server.URL).Use(
+			func(request *http.Request, next api.Next) (*http.Response, error) {
+				order = append(order, "first")
+				request.Header.Set("X-Signature", "signed")
+				return next(request)
+			},
+			func(request *http.Request, next api.Next) (*http.Response, error) {
+				order = append(order, "second")
+				resp, err := next(request)
+				if err == nil {
+					resp.Header.Set("X-Intercepted", "true")
+				}
+				return resp, err
+			},
+		)
+
+		Convey("When we make a call", func() {
+			resp, err := client.GET(postsEndpoint, nil, nil)
+
+			Convey("Then the interceptors modify the request and the response in order", func() {
+				So(err, ShouldBeNil)
+				So(receivedSignature, ShouldEqual, "signed")
+				So(resp.Header.Get("X-Intercepted"), ShouldEqual, "true")
+				So(order, ShouldResemble, []string{"first", "second"})
+			})
+		})
+	})
+}
+
+func TestOnRequestOnResponse(t *testing.T) {
+	Convey("Given a client with request and response hooks", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(10 * time.Millisecond)
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		var requestedPath string
+		var responseStatus int
+		var roundTrip time.Duration
+		client := api.MakeNewClient().WithBasePath(server.URL).
+			WithOnRequest(func(request *http.Request) {
+				requestedPath = request.URL.Path
+			}).
+			WithOnResponse(func(response *http.Response, duration time.Duration) {
+				responseStatus = response.StatusCode
+				roundTrip = duration
+			})
+
+		Convey("When we make a call", func() {
+			_, err := client.GET(postsEndpoint, nil, nil)
+
+			Convey("Then both hooks are called with the call data", func() {
+				So(err, ShouldBeNil)
+				So(requestedPath, ShouldEqual, postsEndpoint)
+				So(responseStatus, ShouldEqual, http.StatusAccepted)
+				So(roundTrip, ShouldBeGreaterThanOrEqualTo, 10*time.Millisecond)
+			})
+		})
+	})
+
+	Convey("Given a client without hooks", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we make a call", func() {
+			_, err := client.GET(postsEndpoint, nil, nil)
+
+			Convey("Then the call succeeds", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
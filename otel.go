@@ -0,0 +1,135 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Names of the instruments created by WithOtelMeter. The duration and in-flight
+// ones follow the OpenTelemetry HTTP client semantic conventions. The errors
+// counter is specific to this library, as the conventions count failures
+// through the error.type attribute of the duration instead; both are recorded.
+const (
+	RequestDurationInstrument = "http.client.request.duration"
+	ActiveRequestsInstrument  = "http.client.active_requests"
+	RequestErrorsInstrument   = "blackbeard.client.request.errors"
+)
+
+// Attributes of the measurements of the calls. The target service set with
+// ToService is a library-specific attribute, as service.name describes the
+// resource emitting the telemetry, not the callee.
+const (
+	serviceAttribute   = "blackbeard.service"
+	errorTypeAttribute = "error.type"
+	otherErrorType     = "_OTHER"
+)
+
+// WithOtelMeter records, with instruments created by the provided OpenTelemetry
+// meter, the duration of every call in seconds, the calls in flight and the
+// failed calls, either because they got no response or an error status. The
+// measurements carry the method, the target service, the status code and, for
+// failed calls, the error type.
+func (client *Client) WithOtelMeter(meter metric.Meter) *Client {
+	instruments, err := newOtelInstruments(meter)
+	if err != nil {
+		client.logger.Errorf("Can't create the otel instruments, metrics are disabled: %v\n", err)
+		return client
+	}
+
+	clone := client.clone()
+	clone.meter = instruments
+	return clone
+}
+
+type otelInstruments struct {
+	duration metric.Float64Histogram
+	active   metric.Int64UpDownCounter
+	errors   metric.Int64Counter
+}
+
+func newOtelInstruments(meter metric.Meter) (*otelInstruments, error) {
+	duration, err := meter.Float64Histogram(
+		RequestDurationInstrument,
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of the client calls"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := meter.Int64UpDownCounter(
+		ActiveRequestsInstrument,
+		metric.WithUnit("{request}"),
+		metric.WithDescription("Client calls in flight"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	failures, err := meter.Int64Counter(
+		RequestErrorsInstrument,
+		metric.WithUnit("{request}"),
+		metric.WithDescription("Failed client calls"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &otelInstruments{duration: duration, active: active, errors: failures}, nil
+}
+
+func (client *Client) meterAttributes(method string) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{attribute.String(methodAttribute, method)}
+	if client.shouldAddService() {
+		attributes = append(attributes, attribute.String(serviceAttribute, client.service))
+	}
+
+	return attributes
+}
+
+func (client *Client) meterStart(request *http.Request) {
+	if client.meter == nil {
+		return
+	}
+
+	client.meter.active.Add(request.Context(), 1, metric.WithAttributes(client.meterAttributes(request.Method)...))
+}
+
+func (client *Client) meterEnd(request *http.Request, response *http.Response, err error, duration time.Duration) {
+	if client.meter == nil {
+		return
+	}
+
+	ctx := request.Context()
+	attributes := client.meterAttributes(request.Method)
+	client.meter.active.Add(ctx, -1, metric.WithAttributes(attributes...))
+
+	if response != nil {
+		attributes = append(attributes, attribute.Int(statusCodeAttribute, response.StatusCode))
+	}
+	failed := err != nil || !IsSuccess(response)
+	if failed {
+		attributes = append(attributes, attribute.String(errorTypeAttribute, errorType(response, err)))
+	}
+	client.meter.duration.Record(ctx, duration.Seconds(), metric.WithAttributes(attributes...))
+
+	if failed {
+		client.meter.errors.Add(ctx, 1, metric.WithAttributes(attributes...))
+	}
+}
+
+// errorType describes a failed call for the error.type attribute: the status
+// code of error responses, or the kind of the failure of calls without one.
+func errorType(response *http.Response, err error) string {
+	if err == nil && response != nil {
+		return strconv.Itoa(response.StatusCode)
+	}
+	if IsTimeoutError(err) {
+		return KindTimeout.String()
+	}
+	return otherErrorType
+}
@@ -0,0 +1,109 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+// collectMetric reads the metric with the provided name from the in-memory
+// reader, if it was recorded.
+func collectMetric(reader sdkmetric.Reader, name string) (metricdata.Aggregation, bool) {
+	resourceMetrics := metricdata.ResourceMetrics{}
+	if err := reader.Collect(context.Background(), &resourceMetrics); err != nil {
+		return nil, false
+	}
+	for _, scope := range resourceMetrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name == name {
+				return m.Data, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func attributeValue(set attribute.Set, key string) attribute.Value {
+	value, _ := set.Value(attribute.Key(key))
+	return value
+}
+
+func TestWithOtelMeter(t *testing.T) {
+	Convey("Given a client with an OpenTelemetry meter read in memory", t, func() {
+		reader := sdkmetric.NewManualReader()
+		provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+		var inFlight int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if data, ok := collectMetric(reader, api.ActiveRequestsInstrument); ok {
+				for _, point := range data.(metricdata.Sum[int64]).DataPoints {
+					inFlight += point.Value
+				}
+			}
+			if r.Method == http.MethodDelete {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).ToService(testTargetService).
+			WithOtelMeter(provider.Meter("blackbeard"))
+
+		Convey("When we make a successful call", func() {
+			checkResponseIsValid(client.GET(postsEndpoint, nil))
+
+			Convey("Then its duration is recorded with the call attributes", func() {
+				data, ok := collectMetric(reader, api.RequestDurationInstrument)
+				So(ok, ShouldBeTrue)
+				points := data.(metricdata.Histogram[float64]).DataPoints
+				So(points, ShouldHaveLength, 1)
+				So(points[0].Count, ShouldEqual, 1)
+				So(points[0].Sum, ShouldBeGreaterThan, 0)
+				So(attributeValue(points[0].Attributes, "http.request.method").AsString(), ShouldEqual, http.MethodGet)
+				So(attributeValue(points[0].Attributes, "blackbeard.service").AsString(), ShouldEqual, testTargetService)
+				So(attributeValue(points[0].Attributes, "http.response.status_code").AsInt64(), ShouldEqual, http.StatusOK)
+				So(points[0].Attributes.HasValue("error.type"), ShouldBeFalse)
+			})
+
+			Convey("Then it was in flight while being served, and no longer is", func() {
+				So(inFlight, ShouldEqual, 1)
+				data, ok := collectMetric(reader, api.ActiveRequestsInstrument)
+				So(ok, ShouldBeTrue)
+				So(data.(metricdata.Sum[int64]).DataPoints[0].Value, ShouldEqual, 0)
+			})
+
+			Convey("Then no error is counted", func() {
+				_, ok := collectMetric(reader, api.RequestErrorsInstrument)
+				So(ok, ShouldBeFalse)
+			})
+		})
+
+		Convey("When a call fails", func() {
+			_, err := client.DELETE(postsEndpoint+"/1", nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then an error is counted with its status code", func() {
+				data, ok := collectMetric(reader, api.RequestErrorsInstrument)
+				So(ok, ShouldBeTrue)
+				points := data.(metricdata.Sum[int64]).DataPoints
+				So(points, ShouldHaveLength, 1)
+				So(points[0].Value, ShouldEqual, 1)
+				So(attributeValue(points[0].Attributes, "http.response.status_code").AsInt64(), ShouldEqual, http.StatusInternalServerError)
+			})
+
+			Convey("Then its duration is recorded with the error type", func() {
+				data, ok := collectMetric(reader, api.RequestDurationInstrument)
+				So(ok, ShouldBeTrue)
+				points := data.(metricdata.Histogram[float64]).DataPoints
+				So(points, ShouldHaveLength, 1)
+				So(attributeValue(points[0].Attributes, "error.type").AsString(), ShouldEqual, "500")
+			})
+		})
+	})
+}
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	limitQuery = "$limit"
+	skipQuery  = "$skip"
+	linkHeader = "Link"
+	nextRel    = "next"
+)
+
+// Count returns the total number of items behind a paginated endpoint without
+// fetching them. It asks the service for an empty page ($limit=0) and returns
+// the reported total.
+func (client *Client) Count(path string, query map[string][]string) (int, error) {
+	countQuery := MergeQueries(query, map[string][]string{limitQuery: {"0"}})
+
+	resp, err := client.GET(path, nil, countQuery)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	paginatedData, err := getPaginatedData(resp)
+	if err != nil {
+		return 0, err
+	}
+
+	return paginatedData.Total, nil
+}
+
+// FetchAllInto GETs every paginated endpoint in receivers, keyed by path, and
+// parses its data into the corresponding receiver, as ParseAllPaginated does.
+// The same query is sent to every endpoint. It stops on the first failure.
+func (client *Client) FetchAllInto(receivers map[string]interface{}, query ...map[string][]string) error {
+	paths := make([]string, 0, len(receivers))
+	for path := range receivers {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		err := client.fetchInto(path, receivers[path], query...)
+		if err != nil {
+			return fmt.Errorf("Can't fetch %v: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+func (client *Client) fetchInto(path string, receiver interface{}, query ...map[string][]string) error {
+	resp, err := client.GET(path, nil, query...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return ParseAllPaginated(resp, receiver)
+}
+
+// PageIterator walks the pages of a paginated endpoint one at a time, so the
+// items don't need to be held in memory at once:
+//
+//	pages := client.Paginate(path, query)
+//	for pages.Next() {
+//		process(pages.Page())
+//	}
+//	err := pages.Err()
+type PageIterator struct {
+	client *Client
+	path   string
+	query  map[string][]string
+	skip   int
+	page   *PaginatedResponse
+	err    error
+	done   bool
+}
+
+// Paginate returns an iterator over the pages of a paginated endpoint. Each
+// page is requested with $skip advanced by the limit of the previous one,
+// until the total is reached.
+func (client *Client) Paginate(path string, query map[string][]string) *PageIterator {
+	return &PageIterator{client: client, path: path, query: query}
+}
+
+// Next fetches the next page, returning false when there are no more pages
+// or the call fails. Check Err afterwards.
+func (iterator *PageIterator) Next() bool {
+	if iterator.done {
+		return false
+	}
+
+	pageQuery := MergeQueries(iterator.query, map[string][]string{skipQuery: {strconv.Itoa(iterator.skip)}})
+
+	resp, err := iterator.client.GET(iterator.path, nil, pageQuery)
+	if err != nil {
+		return iterator.stop(err)
+	}
+	defer resp.Body.Close()
+
+	page, err := getPaginatedData(resp)
+	if err != nil {
+		return iterator.stop(err)
+	}
+
+	if len(page.Data) == 0 {
+		return iterator.stop(nil)
+	}
+
+	step := page.Limit
+	if step == 0 {
+		step = len(page.Data)
+	}
+	iterator.skip += step
+	iterator.done = iterator.skip >= page.Total
+	iterator.page = page
+	return true
+}
+
+func (iterator *PageIterator) stop(err error) bool {
+	iterator.err = err
+	iterator.done = true
+	iterator.page = nil
+	return false
+}
+
+// Page returns the page fetched by the last call to Next.
+func (iterator *PageIterator) Page() *PaginatedResponse {
+	return iterator.page
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (iterator *PageIterator) Err() error {
+	return iterator.err
+}
+
+// NextPageURL returns the URL of the next page of a response paginated with
+// the Link header (RFC 5988), as GitHub-style APIs do. Relative URLs are
+// resolved against the URL of the request.
+func NextPageURL(resp *http.Response) (string, bool) {
+	return LinkURL(resp, nextRel)
+}
+
+// LinkURL returns the URL of the link with the provided relation, like "next"
+// or "last", in the Link header of the response. Relative URLs are resolved
+// against the URL of the request.
+func LinkURL(resp *http.Response, rel string) (string, bool) {
+	for _, header := range resp.Header.Values(linkHeader) {
+		for _, link := range parseLinks(header) {
+			if !link.hasRel(rel) {
+				continue
+			}
+
+			if resp.Request == nil || resp.Request.URL == nil {
+				return link.url, true
+			}
+
+			target, err := resp.Request.URL.Parse(link.url)
+			if err != nil {
+				return link.url, true
+			}
+			return target.String(), true
+		}
+	}
+
+	return "", false
+}
+
+// FollowLink GETs the URL of the link with the provided relation in the Link
+// header of the response, so pagination links can be followed directly. It
+// fails with ErrLinkNotFound if there is no such link. Links to other origins
+// than the one of the client are refused, so a service can't forward the
+// client credentials to another host. The response body is not closed.
+func (client *Client) FollowLink(resp *http.Response, rel string) (*http.Response, error) {
+	target, ok := LinkURL(resp, rel)
+	if !ok {
+		return nil, fmt.Errorf("%w: rel=%q", ErrLinkNotFound, rel)
+	}
+
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if !client.isSameOrigin(targetURL) {
+		return nil, fmt.Errorf("link %v is not under the client origin %v", target, client.getURI())
+	}
+
+	return client.GETAbsolute(target, nil)
+}
+
+type link struct {
+	url  string
+	rels []string
+}
+
+func (l link) hasRel(rel string) bool {
+	for _, candidate := range l.rels {
+		if strings.EqualFold(candidate, rel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseLinks parses the links of a Link header value, like
+// <https://api.example.com/posts?page=2>; rel="next", <...>; rel="last".
+// URLs are delimited by angle brackets, so they can contain commas.
+func parseLinks(header string) []link {
+	var links []link
+	for {
+		start := strings.IndexByte(header, '<')
+		if start < 0 {
+			return links
+		}
+		end := strings.IndexByte(header[start:], '>')
+		if end < 0 {
+			return links
+		}
+
+		current := link{url: header[start+1 : start+end]}
+		header = header[start+end+1:]
+
+		params := header
+		if next := strings.IndexByte(header, '<'); next >= 0 {
+			params = header[:next]
+		}
+		for _, param := range strings.Split(params, ";") {
+			name, value, found := strings.Cut(strings.Trim(param, " ,"), "=")
+			if !found || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+				continue
+			}
+			current.rels = append(current.rels, strings.Fields(strings.Trim(strings.TrimSpace(value), `"`))...)
+		}
+
+		links = append(links, current)
+	}
+}
+
+// GetAllPagesByLink GETs the path and every next page announced by the Link
+// header of the responses, appending the items of each page, a JSON array, to
+// the receiver, which must be a pointer to a slice. Next pages must be under
+// the client URI. It fails with ErrPaginationLoop if a next page was already
+// fetched, so a misbehaving service can't make it loop forever.
+func (client *Client) GetAllPagesByLink(path string, query map[string][]string, receiver interface{}) error {
+	items := reflect.ValueOf(receiver)
+	if items.Kind() != reflect.Ptr || items.Elem().Kind() != reflect.Slice {
+		return NewNotAPointerError()
+	}
+	items = items.Elem()
+
+	visited := map[string]bool{}
+	for {
+		resp, err := client.GET(path, nil, query)
+		if err != nil {
+			return err
+		}
+
+		page := reflect.New(items.Type())
+		err = ParseResponse(resp, page.Interface())
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		items.Set(reflect.AppendSlice(items, page.Elem()))
+		if resp.Request != nil {
+			visited[resp.Request.URL.String()] = true
+		}
+
+		next, ok := NextPageURL(resp)
+		if !ok {
+			return nil
+		}
+		if visited[next] {
+			return fmt.Errorf("%w: %v", ErrPaginationLoop, next)
+		}
+		visited[next] = true
+
+		path, query, err = client.relativeCall(next)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// relativeCall splits an absolute URL under the client URI into the path and
+// query of a call.
+func (client *Client) relativeCall(rawURL string) (string, map[string][]string, error) {
+	absolute, err := url.Parse(rawURL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := absolute.Query()
+	query.Del(keyQuery)
+	absolute.RawQuery = ""
+
+	path := strings.TrimPrefix(absolute.String(), client.getURI())
+	if path == absolute.String() {
+		return "", nil, fmt.Errorf("next page %v is not under the client URI %v", rawURL, client.getURI())
+	}
+
+	return uriSeparator + path, query, nil
+}
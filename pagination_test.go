@@ -0,0 +1,348 @@
+package api_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+const commentsEndpoint = "/comments"
+
+type seedDB struct {
+	Posts []map[string]interface{} `json:"posts"`
+}
+
+func TestCount(t *testing.T) {
+	Convey("Given a paginated service backed by the seed fixture", t, func() {
+		posts := readSeedPosts()
+		var receivedLimit string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			receivedLimit = r.URL.Query().Get("$limit")
+			json.NewEncoder(w).Encode(api.PaginatedResponse{Total: len(posts)})
+		}))
+		defer server.Close()
+
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we count the posts", func() {
+			count, err := client.Count(postsEndpoint, nil)
+
+			Convey("Then the count matches the fixture size without fetching data", func() {
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, len(posts))
+				So(receivedLimit, ShouldEqual, "0")
+			})
+		})
+	})
+}
+
+func readSeedPosts() []map[string]interface{} {
+	data, err := ioutil.ReadFile(serverDBSeed)
+	if err != nil {
+		panic(err)
+	}
+
+	seed := new(seedDB)
+	err = json.Unmarshal(data, seed)
+	if err != nil {
+		panic(err)
+	}
+
+	return seed.Posts
+}
+
+type testComment struct {
+	ID     int    `json:"id"`
+	Body   string `json:"body"`
+	PostID int    `json:"postId"`
+}
+
+func TestFetchAllInto(t *testing.T) {
+	Convey("Given a service with two paginated collections", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case postsEndpoint:
+				w.Write([]byte(`{"total":2,"data":[{"id":1,"title":"json-server"},{"id":2,"title":"Desayuno con diamantes"}]}`))
+			case commentsEndpoint:
+				w.Write([]byte(`{"total":1,"data":[{"id":1,"body":"some comment","postId":1}]}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we fetch both collections", func() {
+			posts := []xmlPost{}
+			comments := []testComment{}
+			err := client.FetchAllInto(map[string]interface{}{
+				postsEndpoint:    &posts,
+				commentsEndpoint: &comments,
+			})
+
+			Convey("Then each collection is decoded into its typed receiver", func() {
+				So(err, ShouldBeNil)
+				So(posts, ShouldHaveLength, 2)
+				So(posts[1].Title, ShouldEqual, "Desayuno con diamantes")
+				So(comments, ShouldResemble, []testComment{{ID: 1, Body: "some comment", PostID: 1}})
+			})
+		})
+
+		Convey("When one of the collections fails", func() {
+			err := client.FetchAllInto(map[string]interface{}{
+				"/wrong": &[]testComment{},
+			})
+
+			Convey("Then we obtain the error of the failed collection", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "/wrong")
+			})
+		})
+	})
+}
+
+func TestNextPageURL(t *testing.T) {
+	Convey("Given responses paginated with the Link header", t, func() {
+		request, err := http.NewRequest(http.MethodGet, "https://api.example.com/v1/posts?page=1", nil)
+		So(err, ShouldBeNil)
+		newLinkedResponse := func(links ...string) *http.Response {
+			resp := newJSONResponse("[]")
+			resp.Request = request
+			for _, link := range links {
+				resp.Header.Add("Link", link)
+			}
+			return resp
+		}
+
+		Convey("When the header has several rel values", func() {
+			resp := newLinkedResponse(`<https://api.example.com/v1/posts?page=1>; rel="first", <https://api.example.com/v1/posts?page=2>; rel="next", <https://api.example.com/v1/posts?page=9>; rel="last"`)
+			next, ok := api.NextPageURL(resp)
+
+			Convey("Then the next one is returned", func() {
+				So(ok, ShouldBeTrue)
+				So(next, ShouldEqual, "https://api.example.com/v1/posts?page=2")
+			})
+		})
+
+		Convey("When the next link shares a quoted rel list and its URL has commas", func() {
+			resp := newLinkedResponse(`<https://api.example.com/v1/posts?ids=1,2;3&page=2>; title="Posts, page 2"; rel="next last"`)
+			next, ok := api.NextPageURL(resp)
+
+			Convey("Then the whole URL is returned", func() {
+				So(ok, ShouldBeTrue)
+				So(next, ShouldEqual, "https://api.example.com/v1/posts?ids=1,2;3&page=2")
+			})
+		})
+
+		Convey("When the links are split in several headers and relative", func() {
+			resp := newLinkedResponse(`</v1/posts?page=0>; rel=prev`, `</v1/posts?page=2>; rel=next`)
+			next, ok := api.NextPageURL(resp)
+
+			Convey("Then the next one is resolved against the request URL", func() {
+				So(ok, ShouldBeTrue)
+				So(next, ShouldEqual, "https://api.example.com/v1/posts?page=2")
+			})
+		})
+
+		Convey("When there is no next link", func() {
+			resp := newLinkedResponse(`<https://api.example.com/v1/posts?page=1>; rel="prev"`)
+			_, ok := api.NextPageURL(resp)
+
+			Convey("Then there is no next page", func() {
+				So(ok, ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestGetAllPagesByLink(t *testing.T) {
+	Convey("Given a service paginating posts with the Link header", t, func() {
+		pages := map[string]string{
+			"1": `[{"id":1},{"id":2}]`,
+			"2": `[{"id":3}]`,
+			"3": `[{"id":4}]`,
+		}
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			page := r.URL.Query().Get("page")
+			if page == "" {
+				page = "1"
+			}
+			if page != "3" {
+				next, _ := strconv.Atoi(page)
+				w.Header().Set("Link", fmt.Sprintf(`<%v/posts?page=%d&per_page=2>; rel="next"`, server.URL, next+1))
+			}
+			w.Write([]byte(pages[page]))
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we get all the pages", func() {
+			var posts []identifiedPost
+			err := client.GetAllPagesByLink(postsEndpoint, map[string][]string{"per_page": {"2"}}, &posts)
+
+			Convey("Then the items of every page are appended", func() {
+				So(err, ShouldBeNil)
+				So(posts, ShouldResemble, []identifiedPost{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}})
+			})
+		})
+
+		Convey("When the receiver is not a pointer to a slice", func() {
+			var post identifiedPost
+			err := client.GetAllPagesByLink(postsEndpoint, nil, &post)
+
+			Convey("Then it fails", func() {
+				So(api.IsNotAPointerError(err), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestGetAllPagesByLinkLoop(t *testing.T) {
+	Convey("Given a service whose next page link points to itself", t, func() {
+		calls := 0
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Link", fmt.Sprintf(`<%v/posts?page=1>; rel="next"`, server.URL))
+			w.Write([]byte(`[{"id":1}]`))
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we get all the pages", func() {
+			var posts []identifiedPost
+			err := client.GetAllPagesByLink(postsEndpoint, map[string][]string{"page": {"1"}}, &posts)
+
+			Convey("Then it stops at the repeated page", func() {
+				So(errors.Is(err, api.ErrPaginationLoop), ShouldBeTrue)
+				So(calls, ShouldEqual, 1)
+				So(posts, ShouldResemble, []identifiedPost{{ID: 1}})
+			})
+		})
+	})
+}
+
+func TestFollowLink(t *testing.T) {
+	Convey("Given a service paginating posts with relative Link headers", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", `</posts?page=2>; rel="next", </posts?page=9>; rel="last"`)
+				w.Write([]byte(`[{"id":1}]`))
+				return
+			}
+			fmt.Fprintf(w, `[{"id":%s}]`, r.URL.Query().Get("page"))
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+		first, err := client.GET(postsEndpoint, nil)
+		So(err, ShouldBeNil)
+
+		Convey("When we follow the next link", func() {
+			resp, err := client.FollowLink(first, "next")
+			So(err, ShouldBeNil)
+			var posts []identifiedPost
+			err = api.ParseResponseTo(resp, &posts)
+
+			Convey("Then we obtain the next page", func() {
+				So(err, ShouldBeNil)
+				So(posts, ShouldResemble, []identifiedPost{{ID: 2}})
+			})
+		})
+
+		Convey("When we follow a link that is not in the header", func() {
+			resp, err := client.FollowLink(first, "prev")
+
+			Convey("Then it fails with ErrLinkNotFound", func() {
+				So(resp, ShouldBeNil)
+				So(errors.Is(err, api.ErrLinkNotFound), ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given a service whose Link header points to another host", t, func() {
+		var stolen string
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			stolen = r.Header.Get(authHeader)
+		}))
+		defer other.Close()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/posts?page=2>; rel="next"`, other.URL))
+			w.Write([]byte(`[{"id":1}]`))
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL).WithAuthHeader(testAuthBearer)
+		first, err := client.GET(postsEndpoint, nil)
+		So(err, ShouldBeNil)
+
+		Convey("When we follow the next link", func() {
+			resp, err := client.FollowLink(first, "next")
+
+			Convey("Then it is refused and the credentials are not sent", func() {
+				So(resp, ShouldBeNil)
+				So(err, ShouldNotBeNil)
+				So(stolen, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestPaginate(t *testing.T) {
+	Convey("Given a service paginating seven posts three at a time", t, func() {
+		var skips []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			skip, _ := strconv.Atoi(r.URL.Query().Get("$skip"))
+			skips = append(skips, r.URL.Query().Get("$skip"))
+			var data []map[string]int
+			for id := skip + 1; id <= 7 && id <= skip+3; id++ {
+				data = append(data, map[string]int{"id": id})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"total": 7, "limit": 3, "skip": skip, "data": data})
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+
+		Convey("When we iterate its pages", func() {
+			pages := client.Paginate(postsEndpoint, map[string][]string{"$limit": {"3"}})
+			consumed, visited := 0, 0
+			for pages.Next() {
+				visited++
+				consumed += len(pages.Page().Data)
+			}
+
+			Convey("Then every item is consumed once", func() {
+				So(pages.Err(), ShouldBeNil)
+				So(visited, ShouldEqual, 3)
+				So(consumed, ShouldEqual, 7)
+				So(skips, ShouldResemble, []string{"0", "3", "6"})
+				So(pages.Next(), ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given a failing service", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		Convey("When we iterate its pages", func() {
+			pages := api.MakeNewClient().WithBasePath(server.URL).Paginate(postsEndpoint, nil)
+
+			Convey("Then the iteration stops with the error", func() {
+				So(pages.Next(), ShouldBeFalse)
+				So(pages.Err(), ShouldNotBeNil)
+				So(pages.Page(), ShouldBeNil)
+			})
+		})
+	})
+}
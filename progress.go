@@ -0,0 +1,95 @@
+package api
+
+import (
+	"io"
+	"net/http"
+)
+
+// ProgressFunc receives the bytes of a request body sent so far and the total
+// size of the body, or -1 when it is not known upfront.
+type ProgressFunc func(written, total int64)
+
+// WithUploadProgress reports the progress of request bodies, including plain
+// io.Reader bodies, while they are sent to the service.
+func (client *Client) WithUploadProgress(progress ProgressFunc) *Client {
+	clone := client.clone()
+	clone.progress = progress
+	return clone
+}
+
+func (client *Client) trackUploadProgress(request *http.Request) {
+	if client.progress == nil || request.Body == nil || request.Body == http.NoBody {
+		return
+	}
+
+	total := request.ContentLength
+	if total <= 0 {
+		total = -1
+	}
+
+	request.Body = &progressReader{ReadCloser: request.Body, total: total, progress: client.progress}
+	if getBody := request.GetBody; getBody != nil {
+		request.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return &progressReader{ReadCloser: body, total: total, progress: client.progress}, nil
+		}
+	}
+}
+
+type progressReader struct {
+	io.ReadCloser
+	written  int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (reader *progressReader) Read(p []byte) (int, error) {
+	n, err := reader.ReadCloser.Read(p)
+	if n > 0 {
+		reader.written += int64(n)
+		reader.progress(reader.written, reader.total)
+	}
+	return n, err
+}
+
+// multipartProgress reports the bytes of the files written to a multipart body.
+type multipartProgress struct {
+	written  int64
+	total    int64
+	progress ProgressFunc
+}
+
+func newMultipartProgress(data MultipartBody) *multipartProgress {
+	if data.Progress == nil {
+		return nil
+	}
+
+	return &multipartProgress{total: multipartFilesSize(data), progress: data.Progress}
+}
+
+// track wraps the writer of a part so the bytes written to it are reported.
+// It is a no-op on a nil tracker.
+func (tracker *multipartProgress) track(part io.Writer) io.Writer {
+	if tracker == nil {
+		return part
+	}
+
+	return &progressWriter{Writer: part, tracker: tracker}
+}
+
+type progressWriter struct {
+	io.Writer
+	tracker *multipartProgress
+}
+
+func (writer *progressWriter) Write(p []byte) (int, error) {
+	n, err := writer.Writer.Write(p)
+	if n > 0 {
+		writer.tracker.written += int64(n)
+		writer.tracker.progress(writer.tracker.written, writer.tracker.total)
+	}
+	return n, err
+}
@@ -0,0 +1,67 @@
+package api_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func TestWithUploadProgress(t *testing.T) {
+	Convey("Given a service that reads the request body", t, func() {
+		var received int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			received = len(body)
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		payload := bytes.Repeat([]byte("a"), 1<<20)
+		var written, totals []int64
+		client := api.MakeNewClient().WithBasePath(server.URL).
+			WithUploadProgress(func(sent, total int64) {
+				written = append(written, sent)
+				totals = append(totals, total)
+			})
+
+		Convey("When we POST a large reader of known size", func() {
+			resp, err := client.POST(postsEndpoint, bytes.NewReader(payload))
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the progress is reported up to the full size", func() {
+				So(received, ShouldEqual, len(payload))
+				So(len(written), ShouldBeGreaterThan, 1)
+				So(written[len(written)-1], ShouldEqual, len(payload))
+				So(totals[0], ShouldEqual, len(payload))
+				for i := 1; i < len(written); i++ {
+					So(written[i], ShouldBeGreaterThan, written[i-1])
+				}
+			})
+		})
+
+		Convey("When we POST a reader of unknown size", func() {
+			reader, writer := io.Pipe()
+			go func() {
+				writer.Write(payload)
+				writer.Close()
+			}()
+			resp, err := client.POST(postsEndpoint, reader)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the total is reported as unknown", func() {
+				So(received, ShouldEqual, len(payload))
+				So(written[len(written)-1], ShouldEqual, len(payload))
+				So(totals[0], ShouldEqual, -1)
+			})
+		})
+	})
+}
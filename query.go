@@ -0,0 +1,292 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	queryTag       = "query"
+	urlTag         = "url"
+	omitEmptyFlag  = "omitempty"
+	skipFieldValue = "-"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// QueryFromStruct builds a query from the exported fields of a struct, or a
+// pointer to one, so it can be passed to the call methods. Fields are encoded
+// using their `query` tag or, as in github.com/google/go-querystring, their
+// `url` tag:
+//
+//	Title  string     `query:"title"`         // title=...
+//	Tags   []string   `query:"tag,omitempty"` // tag=a&tag=b, skipped if empty
+//	Groups [][]string `query:"group"`         // every nested value as group=...
+//	Secret string     `query:"-"`             // never encoded
+//
+// Untagged fields use the field name. Strings, numbers, bools, time.Time and
+// (nested) slices or pointers of them are supported, and embedded structs are
+// flattened.
+func QueryFromStruct(v interface{}) (map[string][]string, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return map[string][]string{}, nil
+		}
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Can't build a query from %T: not a struct", v)
+	}
+
+	query := map[string][]string{}
+	err := addStructToQuery(query, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+// MergeQueries merges the provided queries, in order, into a new one, so
+// later queries take precedence: when a key is present in several of them,
+// the values of the last one win, replacing, not adding to, the previous
+// ones. The provided queries are never modified. The result is nil when all
+// of them are nil, as when no query is provided.
+func MergeQueries(queries ...map[string][]string) map[string][]string {
+	var merged map[string][]string
+	for _, query := range queries {
+		if query == nil {
+			continue
+		}
+		if merged == nil {
+			merged = map[string][]string{}
+		}
+
+		for key, values := range query {
+			merged[key] = append([]string(nil), values...)
+		}
+	}
+
+	return merged
+}
+
+func addStructToQuery(query map[string][]string, value reflect.Value) error {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		name, omitEmpty := parseQueryTag(field)
+		if name == skipFieldValue {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		if field.Anonymous && indirectType(field.Type).Kind() == reflect.Struct && fieldQueryTag(field) == "" {
+			fieldValue = indirect(fieldValue)
+			if fieldValue.IsValid() {
+				err := addStructToQuery(query, fieldValue)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if omitEmpty && isEmptyValue(fieldValue) {
+			continue
+		}
+
+		values, err := queryValues(indirect(fieldValue))
+		if err != nil {
+			return fmt.Errorf("Can't encode query field %v: %v", field.Name, err)
+		}
+		if values != nil {
+			query[name] = append(query[name], values...)
+		}
+	}
+
+	return nil
+}
+
+func fieldQueryTag(field reflect.StructField) string {
+	if tag, ok := field.Tag.Lookup(queryTag); ok {
+		return tag
+	}
+	return field.Tag.Get(urlTag)
+}
+
+func parseQueryTag(field reflect.StructField) (name string, omitEmpty bool) {
+	options := strings.Split(fieldQueryTag(field), ",")
+	name = options[0]
+	if name == "" {
+		name = field.Name
+	}
+
+	for _, option := range options[1:] {
+		if option == omitEmptyFlag {
+			omitEmpty = true
+		}
+	}
+
+	return name, omitEmpty
+}
+
+func queryValues(value reflect.Value) ([]string, error) {
+	if !value.IsValid() {
+		return nil, nil
+	}
+
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		values := make([]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			elemValues, err := queryValues(indirect(value.Index(i)))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, elemValues...)
+		}
+		return values, nil
+	}
+
+	formatted, err := formatQueryValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{formatted}, nil
+}
+
+func formatQueryValue(value reflect.Value) (string, error) {
+	if value.Type() == timeType {
+		return value.Interface().(time.Time).Format(time.RFC3339), nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, value.Type().Bits()), nil
+	default:
+		return "", fmt.Errorf("unsupported type %v", value.Type())
+	}
+}
+
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return value.IsNil()
+	}
+
+	if value.Type() == timeType {
+		return value.Interface().(time.Time).IsZero()
+	}
+
+	return false
+}
+
+func indirect(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+
+	return value
+}
+
+func indirectType(valueType reflect.Type) reflect.Type {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+
+	return valueType
+}
+
+// FeathersOperator is a feathers query operator.
+type FeathersOperator string
+
+// Feathers query operators supported by the query helpers.
+const (
+	FeathersIn          FeathersOperator = "$in"
+	FeathersNotIn       FeathersOperator = "$nin"
+	FeathersLessThan    FeathersOperator = "$lt"
+	FeathersGreaterThan FeathersOperator = "$gt"
+	FeathersLike        FeathersOperator = "$like"
+)
+
+// QueryScalar are the types of the values of the feathers query helpers.
+type QueryScalar interface {
+	~string | ~bool |
+		~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// FeathersQuery builds the query of a feathers operator on a field, nested as
+// feathers expects it: field[$lt]=10, or field[$in][]=a&field[$in][]=b for
+// the operators that take a list. Queries on different fields or operators
+// can be passed together to the call methods, which merge them.
+func FeathersQuery[T QueryScalar](field string, operator FeathersOperator, values ...T) map[string][]string {
+	key := fmt.Sprintf("%s[%s]", field, operator)
+	if operator == FeathersIn || operator == FeathersNotIn {
+		key += "[]"
+	}
+
+	formatted := make([]string, len(values))
+	for i, value := range values {
+		formatted[i] = fmt.Sprint(value)
+	}
+
+	return map[string][]string{key: formatted}
+}
+
+// QueryIn matches the items whose field is any of the values.
+func QueryIn[T QueryScalar](field string, values ...T) map[string][]string {
+	return FeathersQuery(field, FeathersIn, values...)
+}
+
+// QueryNotIn matches the items whose field is none of the values.
+func QueryNotIn[T QueryScalar](field string, values ...T) map[string][]string {
+	return FeathersQuery(field, FeathersNotIn, values...)
+}
+
+// QueryLessThan matches the items whose field is less than the value.
+func QueryLessThan[T QueryScalar](field string, value T) map[string][]string {
+	return FeathersQuery(field, FeathersLessThan, value)
+}
+
+// QueryGreaterThan matches the items whose field is greater than the value.
+func QueryGreaterThan[T QueryScalar](field string, value T) map[string][]string {
+	return FeathersQuery(field, FeathersGreaterThan, value)
+}
+
+// QueryLike matches the items whose field matches the SQL LIKE pattern, like
+// "%Capote%". Only SQL adapters support it.
+func QueryLike(field, pattern string) map[string][]string {
+	return FeathersQuery(field, FeathersLike, pattern)
+}
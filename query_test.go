@@ -0,0 +1,208 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+type Pagination struct {
+	Limit int `url:"$limit,omitempty"`
+	Skip  int `url:"$skip,omitempty"`
+}
+
+type postFilter struct {
+	Pagination
+	Author    string   `url:"author"`
+	Title     string   `url:"title,omitempty"`
+	Tags      []string `url:"tag,omitempty"`
+	IDs       []int    `url:"id"`
+	Published *bool    `url:"published,omitempty"`
+	Rating    float64
+	Secret    string `url:"-"`
+}
+
+func TestQueryFromStruct(t *testing.T) {
+	Convey("Given a filter struct with url tags", t, func() {
+		published := true
+		filter := postFilter{
+			Pagination: Pagination{Limit: 10},
+			Author:     "Truman Capote",
+			Tags:       []string{"novel", "classic"},
+			IDs:        []int{1, 2},
+			Published:  &published,
+			Rating:     4.5,
+			Secret:     "hidden",
+		}
+
+		Convey("When we build a query from it", func() {
+			query, err := api.QueryFromStruct(&filter)
+
+			Convey("Then the fields are encoded following their tags", func() {
+				So(err, ShouldBeNil)
+				So(query, ShouldResemble, map[string][]string{
+					"$limit":    {"10"},
+					"author":    {"Truman Capote"},
+					"tag":       {"novel", "classic"},
+					"id":        {"1", "2"},
+					"published": {"true"},
+					"Rating":    {"4.5"},
+				})
+			})
+		})
+	})
+
+	Convey("Given a value that is not a struct", t, func() {
+		Convey("When we build a query from it", func() {
+			_, err := api.QueryFromStruct("title")
+
+			Convey("Then we obtain an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+type commentFilter struct {
+	PostID   int        `query:"postId"`
+	Body     string     `query:"body,omitempty"`
+	Approved bool       `query:"approved,omitempty"`
+	Scores   []float64  `query:"score,omitempty"`
+	Groups   [][]string `query:"group"`
+	Authors  []*string  `query:"author,omitempty"`
+	Legacy   string     `url:"legacy,omitempty"`
+	Internal string     `query:"-" url:"internal"`
+}
+
+func TestQueryFromStructQueryTags(t *testing.T) {
+	Convey("Given a filter struct with query tags and nested slices", t, func() {
+		author := "Truman Capote"
+		filter := commentFilter{
+			PostID:   1,
+			Groups:   [][]string{{"a", "b"}, {"c"}},
+			Authors:  []*string{&author, nil},
+			Internal: "hidden",
+		}
+
+		Convey("When we build a query from it", func() {
+			query, err := api.QueryFromStruct(filter)
+
+			Convey("Then nested values are flattened and empty tagged values skipped", func() {
+				So(err, ShouldBeNil)
+				So(query, ShouldResemble, map[string][]string{
+					"postId": {"1"},
+					"group":  {"a", "b", "c"},
+					"author": {"Truman Capote"},
+				})
+			})
+		})
+
+		Convey("When the omitempty fields have values", func() {
+			filter.Body = "some comment"
+			filter.Approved = true
+			filter.Scores = []float64{0.5, 2}
+			filter.Legacy = "yes"
+			query, err := api.QueryFromStruct(filter)
+
+			Convey("Then they are encoded", func() {
+				So(err, ShouldBeNil)
+				So(query["body"], ShouldResemble, []string{"some comment"})
+				So(query["approved"], ShouldResemble, []string{"true"})
+				So(query["score"], ShouldResemble, []string{"0.5", "2"})
+				So(query["legacy"], ShouldResemble, []string{"yes"})
+				So(query, ShouldNotContainKey, "internal")
+			})
+		})
+	})
+}
+
+func TestFeathersQuery(t *testing.T) {
+	Convey("Given the feathers query helpers", t, func() {
+		encode := func(query map[string][]string) string {
+			decoded, err := url.QueryUnescape(url.Values(query).Encode())
+			So(err, ShouldBeNil)
+			return decoded
+		}
+
+		Convey("When we build a query for each operator", func() {
+			in := encode(api.QueryIn("author", "Truman Capote", "Harper Lee"))
+			notIn := encode(api.QueryNotIn("id", 1, 2))
+			lessThan := encode(api.QueryLessThan("year", 1966))
+			greaterThan := encode(api.QueryGreaterThan("rating", 4.5))
+			like := encode(api.QueryLike("title", "%diamantes%"))
+
+			Convey("Then the parameters are nested as feathers expects", func() {
+				So(in, ShouldEqual, "author[$in][]=Truman Capote&author[$in][]=Harper Lee")
+				So(notIn, ShouldEqual, "id[$nin][]=1&id[$nin][]=2")
+				So(lessThan, ShouldEqual, "year[$lt]=1966")
+				So(greaterThan, ShouldEqual, "rating[$gt]=4.5")
+				So(like, ShouldEqual, "title[$like]=%diamantes%")
+			})
+		})
+
+		Convey("When we combine several operators in a call", func() {
+			var rawQuery string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rawQuery = r.URL.RawQuery
+			}))
+			defer server.Close()
+			client := api.MakeNewClient().WithBasePath(server.URL)
+			_, err := client.GET(postsEndpoint, nil, api.QueryGreaterThan("year", 1950), api.QueryLessThan("year", 1970))
+			So(err, ShouldBeNil)
+			decoded, err := url.QueryUnescape(rawQuery)
+
+			Convey("Then all of them are sent", func() {
+				So(err, ShouldBeNil)
+				So(decoded, ShouldEqual, "year[$gt]=1950&year[$lt]=1970")
+			})
+		})
+	})
+}
+
+func TestMergeQueries(t *testing.T) {
+	Convey("Given a default query, a per-call query and an override", t, func() {
+		defaults := map[string][]string{"$limit": {"10"}, "tag": {"novel", "classic"}}
+		call := map[string][]string{"tag": {"essay"}, "author": {"Truman Capote"}}
+		override := map[string][]string{"$limit": {"5"}}
+
+		Convey("When we merge them", func() {
+			merged := api.MergeQueries(defaults, nil, call, override)
+			merged["author"][0] = "Harper Lee"
+
+			Convey("Then the values of the last query with each key win", func() {
+				So(merged, ShouldResemble, map[string][]string{
+					"$limit": {"5"},
+					"tag":    {"essay"},
+					"author": {"Harper Lee"},
+				})
+				So(url.Values(merged).Encode(), ShouldEqual, "%24limit=5&author=Harper+Lee&tag=essay")
+			})
+
+			Convey("Then the provided queries are untouched", func() {
+				So(defaults["$limit"], ShouldResemble, []string{"10"})
+				So(call["author"], ShouldResemble, []string{"Truman Capote"})
+			})
+		})
+
+		Convey("When we merge them in the opposite order", func() {
+			merged := api.MergeQueries(override, call, defaults)
+
+			Convey("Then the defaults win", func() {
+				So(merged["$limit"], ShouldResemble, []string{"10"})
+				So(merged["tag"], ShouldResemble, []string{"novel", "classic"})
+			})
+		})
+
+		Convey("When we merge no queries", func() {
+			Convey("Then we obtain no query", func() {
+				So(api.MergeQueries(), ShouldBeNil)
+				So(api.MergeQueries(nil, nil), ShouldBeNil)
+			})
+		})
+	})
+}
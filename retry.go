@@ -0,0 +1,103 @@
+package api
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const retryAfterHeader = "Retry-After"
+
+// RetryAfterBackoff computes the wait before retrying a call the service
+// answered with a Retry-After header. The wait is the Retry-After value plus a
+// full jitter up to Cap, so clients told to come back at the same time don't
+// do it all at once. A Retry-After beyond Cap is capped.
+type RetryAfterBackoff struct {
+	// Cap is the maximum wait. Zero means no cap, and then the wait is just
+	// the Retry-After value.
+	Cap time.Duration
+	// Jitter returns a random number in [0, n). It defaults to rand.Int63n.
+	Jitter func(n int64) int64
+}
+
+// Wait returns the wait for the provided Retry-After value, within
+// [retryAfter, Cap].
+func (backoff RetryAfterBackoff) Wait(retryAfter time.Duration) time.Duration {
+	if backoff.Cap <= 0 {
+		return retryAfter
+	}
+	if retryAfter >= backoff.Cap {
+		return backoff.Cap
+	}
+
+	jitter := backoff.Jitter
+	if jitter == nil {
+		jitter = rand.Int63n
+	}
+
+	return retryAfter + time.Duration(jitter(int64(backoff.Cap-retryAfter)+1))
+}
+
+// WithRetryAfter retries, up to maxRetries times, the calls answered with a
+// 429 or 503 status and a Retry-After header, waiting as backoff says. Calls
+// whose body can't be sent again are not retried.
+func (client *Client) WithRetryAfter(maxRetries int, backoff RetryAfterBackoff) *Client {
+	return client.Use(func(request *http.Request, next Next) (*http.Response, error) {
+		for attempt := 0; ; attempt++ {
+			response, err := next(request)
+			if err != nil || attempt >= maxRetries {
+				return response, err
+			}
+
+			retryAfter, ok := parseRetryAfter(response)
+			if !ok || (request.Body != nil && request.GetBody == nil) {
+				return response, err
+			}
+
+			closeBody(response)
+			timer := time.NewTimer(backoff.Wait(retryAfter))
+			select {
+			case <-timer.C:
+			case <-request.Context().Done():
+				timer.Stop()
+				return nil, request.Context().Err()
+			}
+
+			if request.GetBody != nil {
+				request.Body, err = request.GetBody()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	})
+}
+
+// parseRetryAfter returns the Retry-After of a retryable response, given in
+// either seconds or as an HTTP date.
+func parseRetryAfter(response *http.Response) (time.Duration, bool) {
+	if response.StatusCode != http.StatusTooManyRequests && response.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	value := response.Header.Get(retryAfterHeader)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	wait := time.Until(date)
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
@@ -0,0 +1,98 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func TestRetryAfterBackoff(t *testing.T) {
+	Convey("Given a backoff capped at 10 seconds", t, func() {
+		backoff := api.RetryAfterBackoff{Cap: 10 * time.Second}
+		retryAfter := 2 * time.Second
+
+		Convey("When we compute the wait of many calls told to retry after 2 seconds", func() {
+			waits := map[time.Duration]bool{}
+			inRange := true
+			for i := 0; i < 100; i++ {
+				wait := backoff.Wait(retryAfter)
+				waits[wait] = true
+				inRange = inRange && wait >= retryAfter && wait <= backoff.Cap
+			}
+
+			Convey("Then every wait stays within [retryAfter, cap] and jitter spreads them", func() {
+				So(inRange, ShouldBeTrue)
+				So(len(waits), ShouldBeGreaterThan, 1)
+			})
+		})
+
+		Convey("When the jitter is at its bounds", func() {
+			lowest := api.RetryAfterBackoff{Cap: backoff.Cap, Jitter: func(n int64) int64 { return 0 }}
+			highest := api.RetryAfterBackoff{Cap: backoff.Cap, Jitter: func(n int64) int64 { return n - 1 }}
+
+			Convey("Then the wait spans the whole range", func() {
+				So(lowest.Wait(retryAfter), ShouldEqual, retryAfter)
+				So(highest.Wait(retryAfter), ShouldEqual, backoff.Cap)
+			})
+		})
+
+		Convey("When the Retry-After is beyond the cap", func() {
+			Convey("Then the wait is capped", func() {
+				So(backoff.Wait(time.Minute), ShouldEqual, backoff.Cap)
+			})
+		})
+	})
+
+	Convey("Given a backoff without a cap", t, func() {
+		backoff := api.RetryAfterBackoff{}
+
+		Convey("When we compute the wait of a call told to retry after 3 seconds", func() {
+			wait := backoff.Wait(3 * time.Second)
+
+			Convey("Then the Retry-After is honored", func() {
+				So(wait, ShouldEqual, 3*time.Second)
+			})
+		})
+	})
+}
+
+func TestWithRetryAfter(t *testing.T) {
+	Convey("Given a service that rate limits the first call", t, func() {
+		calls := new(int32)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(calls, 1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		Convey("When a client honoring Retry-After calls it", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).
+				WithRetryAfter(2, api.RetryAfterBackoff{Cap: 20 * time.Millisecond})
+			resp, err := client.POST(postsEndpoint, map[string]string{"title": "Desayuno con diamantes"})
+
+			Convey("Then the call is retried after the wait", func() {
+				checkResponseIsValid(resp, err)
+				So(atomic.LoadInt32(calls), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When a client without retries calls it", func() {
+			resp, err := api.MakeNewClient().WithBasePath(server.URL).GET(postsEndpoint, nil)
+
+			Convey("Then the rate limited response is returned", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusTooManyRequests)
+			})
+		})
+	})
+}
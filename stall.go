@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+func (client *Client) withStallCancel(request *http.Request) (*http.Request, context.CancelFunc) {
+	if client.stall <= 0 {
+		return request, func() {}
+	}
+
+	ctx, cancel := context.WithCancel(request.Context())
+	return request.WithContext(ctx), cancel
+}
+
+// watchStall wraps the response body so reading it is aborted through cancel
+// if no bytes arrive within the stall timeout.
+func (client *Client) watchStall(response *http.Response, cancel context.CancelFunc) {
+	if client.stall <= 0 {
+		return
+	}
+
+	response.Body = &stallReader{
+		ReadCloser: response.Body,
+		timeout:    client.stall,
+		cancel:     cancel,
+	}
+}
+
+type stallReader struct {
+	io.ReadCloser
+	timeout time.Duration
+	cancel  context.CancelFunc
+	stalled int32
+}
+
+func (body *stallReader) Read(p []byte) (int, error) {
+	timer := time.AfterFunc(body.timeout, body.abort)
+	n, err := body.ReadCloser.Read(p)
+	timer.Stop()
+
+	if err != nil && atomic.LoadInt32(&body.stalled) == 1 {
+		return n, &StalledTransferError{Timeout: body.timeout}
+	}
+
+	return n, err
+}
+
+func (body *stallReader) abort() {
+	atomic.StoreInt32(&body.stalled, 1)
+	body.cancel()
+}
+
+func (body *stallReader) Close() error {
+	err := body.ReadCloser.Close()
+	body.cancel()
+	return err
+}
+
+// StalledTransferError is used when no bytes of a response body arrive within
+// the stall timeout.
+type StalledTransferError struct {
+	Timeout time.Duration
+}
+
+func (e *StalledTransferError) Error() string {
+	return fmt.Sprintf("Transfer stalled: no bytes received in %v", e.Timeout)
+}
+
+// IsStalledTransferError checks if the error is a StalledTransferError error.
+func IsStalledTransferError(err error) bool {
+	_, ok := err.(*StalledTransferError)
+	return ok
+}
@@ -0,0 +1,50 @@
+package api_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func TestWithStallTimeout(t *testing.T) {
+	Convey("Given a service that stalls in the middle of a response", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"title":`))
+			w.(http.Flusher).Flush()
+			time.Sleep(300 * time.Millisecond)
+			w.Write([]byte(`"json-server"}`))
+		}))
+		defer server.Close()
+
+		Convey("When we read the response with a short stall timeout", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithStallTimeout(50 * time.Millisecond)
+			resp, err := client.GET(postsEndpoint, nil, nil)
+			So(err, ShouldBeNil)
+			_, err = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			Convey("Then we obtain a stall error", func() {
+				So(api.IsStalledTransferError(err), ShouldBeTrue)
+			})
+		})
+
+		Convey("When we read the response with a longer stall timeout", func() {
+			client := api.MakeNewClient().WithBasePath(server.URL).WithStallTimeout(time.Second)
+			resp, err := client.GET(postsEndpoint, nil, nil)
+			So(err, ShouldBeNil)
+			body, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			Convey("Then the whole body is read", func() {
+				So(err, ShouldBeNil)
+				So(string(body), ShouldEqual, `{"title":"json-server"}`)
+			})
+		})
+	})
+}
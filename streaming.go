@@ -0,0 +1,151 @@
+package api
+
+import (
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// MULTIPARTStream performs the same call as MULTIPART, but streaming the body
+// to the service while it is written, instead of buffering the whole payload
+// in memory first. When the size of every file is known upfront, as with files
+// on disk or seekable readers, the Content-Length of the call is set too.
+// Otherwise the body is sent chunked.
+func (client *Client) MULTIPARTStream(
+	path string,
+	bodyData MultipartBody,
+	query map[string][]string,
+) (*http.Response, error) {
+
+	body := newMultipartStream(bodyData)
+	headers := client.snapshotHeaders().Clone()
+	headers.Set(contentTypeHeader, body.contentType)
+
+	resp, err := client.executeCallWithHeaders(http.MethodPost, path, body, query, headers)
+	if err != nil {
+		// Unblock the writer if the body was never sent.
+		body.Close()
+	}
+
+	return resp, err
+}
+
+type multipartStream struct {
+	*io.PipeReader
+	contentType string
+	size        int64
+}
+
+func newMultipartStream(data MultipartBody) *multipartStream {
+	reader, pipe := io.Pipe()
+	writer := multipart.NewWriter(pipe)
+	stream := &multipartStream{
+		PipeReader:  reader,
+		contentType: writer.FormDataContentType(),
+		size:        multipartSize(data, writer.Boundary()),
+	}
+
+	go func() {
+		err := writeMultipartBody(writer, data)
+		if err == nil {
+			err = writer.Close()
+		}
+		pipe.CloseWithError(err)
+	}()
+
+	return stream
+}
+
+func (stream *multipartStream) Size() int64 {
+	return stream.size
+}
+
+// multipartSize computes the size of the multipart body writing it with empty
+// parts, and adding the size of the files. It returns -1 if the size of any
+// file is unknown.
+func multipartSize(data MultipartBody, boundary string) int64 {
+	counter := &countingWriter{}
+	writer := multipart.NewWriter(counter)
+	err := writer.SetBoundary(boundary)
+	if err != nil {
+		return -1
+	}
+
+	filesSize := multipartFilesSize(data)
+	if filesSize < 0 {
+		return -1
+	}
+
+	for key, path := range data.Files {
+		_, _ = writer.CreateFormFile(key, filepath.Base(path))
+	}
+
+	for key, reader := range data.FileReaders {
+		_, _ = writer.CreateFormFile(key, readerFilename(key, reader))
+	}
+
+	for key, val := range data.Params {
+		_ = writer.WriteField(key, val)
+	}
+	_ = writer.Close()
+
+	return counter.written + filesSize
+}
+
+// multipartFilesSize returns the size of all the files of the multipart body,
+// or -1 if the size of any of them is unknown.
+func multipartFilesSize(data MultipartBody) int64 {
+	var size int64
+	for _, path := range data.Files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return -1
+		}
+		size += info.Size()
+	}
+
+	for _, reader := range data.FileReaders {
+		readerSize := readerSize(reader)
+		if readerSize < 0 {
+			return -1
+		}
+		size += readerSize
+	}
+
+	return size
+}
+
+// readerSize returns the bytes left to read in a seekable reader, or -1 if the
+// reader can't seek.
+func readerSize(reader io.Reader) int64 {
+	seeker, ok := reader.(io.Seeker)
+	if !ok {
+		return -1
+	}
+
+	current, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1
+	}
+	_, err = seeker.Seek(current, io.SeekStart)
+	if err != nil {
+		return -1
+	}
+
+	return end - current
+}
+
+type countingWriter struct {
+	written int64
+}
+
+func (counter *countingWriter) Write(p []byte) (int, error) {
+	counter.written += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,91 @@
+package api_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func TestMULTIPARTStream(t *testing.T) {
+	Convey("Given a service that reads multipart uploads", t, func() {
+		var contentLength int64
+		var received int64
+		var title string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentLength = r.ContentLength
+			reader, err := r.MultipartReader()
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			for {
+				part, err := reader.NextPart()
+				if err != nil {
+					break
+				}
+				if part.FileName() == "" {
+					value, _ := ioutil.ReadAll(part)
+					title = string(value)
+					continue
+				}
+				received, _ = io.Copy(ioutil.Discard, part)
+			}
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+		params := map[string]string{"title": "Desayuno con diamantes"}
+
+		Convey("When we stream a large file from disk", func() {
+			dir, err := ioutil.TempDir("", "blackbeard")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "large.bin")
+			size := int64(32 << 20)
+			So(ioutil.WriteFile(path, bytes.Repeat([]byte("a"), int(size)), 0644), ShouldBeNil)
+
+			resp, err := client.MULTIPARTStream(postsEndpoint, api.NewMultipartBody(params, map[string]string{"file": path}), nil)
+
+			Convey("Then the whole file is sent with a known Content-Length", func() {
+				checkResponseIsValid(resp, err)
+				So(received, ShouldEqual, size)
+				So(title, ShouldEqual, "Desayuno con diamantes")
+				So(contentLength, ShouldBeGreaterThan, size)
+			})
+		})
+
+		Convey("When we stream a reader of unknown size", func() {
+			reader, writer := io.Pipe()
+			go func() {
+				writer.Write(bytes.Repeat([]byte("a"), 1<<20))
+				writer.Close()
+			}()
+
+			body := api.NewMultipartBodyWithReaders(params, map[string]io.Reader{"file": reader})
+			resp, err := client.MULTIPARTStream(postsEndpoint, body, nil)
+
+			Convey("Then it is sent chunked", func() {
+				checkResponseIsValid(resp, err)
+				So(received, ShouldEqual, 1<<20)
+				So(contentLength, ShouldEqual, -1)
+			})
+		})
+
+		Convey("When we stream a file that does not exist", func() {
+			body := api.NewMultipartBody(params, map[string]string{"file": "testdata/missing.bin"})
+			_, err := client.MULTIPARTStream(postsEndpoint, body, nil)
+
+			Convey("Then the call fails", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes of the spans and measurements of the calls, following the
+// OpenTelemetry HTTP client semantic conventions.
+const (
+	methodAttribute     = "http.request.method"
+	statusCodeAttribute = "http.response.status_code"
+)
+
+// WithTracer starts a client span with the provided OpenTelemetry tracer
+// around each call, named by the method and the target service, and
+// propagates it to the service through the W3C traceparent header.
+func (client *Client) WithTracer(tracer trace.Tracer) *Client {
+	clone := client.clone()
+	clone.tracer = tracer
+	return clone
+}
+
+func (client *Client) startSpan(request *http.Request) (*http.Request, trace.Span) {
+	if client.tracer == nil {
+		return request, nil
+	}
+
+	ctx, span := client.tracer.Start(
+		request.Context(),
+		client.spanName(request.Method),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String(methodAttribute, request.Method)),
+	)
+	request = request.WithContext(ctx)
+	request.Header = request.Header.Clone()
+	if request.Header == nil {
+		request.Header = http.Header{}
+	}
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(request.Header))
+
+	return request, span
+}
+
+func (client *Client) endSpan(span trace.Span, response *http.Response, err error) {
+	if span == nil {
+		return
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	if response != nil {
+		span.SetAttributes(attribute.Int(statusCodeAttribute, response.StatusCode))
+		if response.StatusCode >= http.StatusBadRequest {
+			span.SetStatus(codes.Error, http.StatusText(response.StatusCode))
+		}
+	}
+	span.End()
+}
+
+func (client *Client) spanName(method string) string {
+	if !client.shouldAddService() {
+		return method
+	}
+	return fmt.Sprintf("%s %s", method, client.service)
+}
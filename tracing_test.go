@@ -0,0 +1,74 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+// spanAttribute returns the value of an attribute of the span, if any.
+func spanAttribute(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestWithTracer(t *testing.T) {
+	Convey("Given a client with an OpenTelemetry tracer", t, func() {
+		var traceParent string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			traceParent = r.Header.Get("traceparent")
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		client := api.MakeNewClient().WithBasePath(server.URL).ToService(testTargetService).
+			WithTracer(provider.Tracer("blackbeard"))
+
+		Convey("When we make a call", func() {
+			_, err := client.POST(postsEndpoint, testPost, nil)
+			So(err, ShouldBeNil)
+
+			Convey("Then a client span is recorded and propagated to the service", func() {
+				spans := recorder.Ended()
+				So(spans, ShouldHaveLength, 1)
+				span := spans[0]
+				So(span.Name(), ShouldEqual, "POST "+testTargetService)
+				So(span.SpanKind(), ShouldEqual, trace.SpanKindClient)
+				So(spanAttribute(span, "http.request.method").AsString(), ShouldEqual, http.MethodPost)
+				So(spanAttribute(span, "http.response.status_code").AsInt64(), ShouldEqual, http.StatusCreated)
+				So(span.Status().Code, ShouldEqual, codes.Unset)
+				expected := "00-" + span.SpanContext().TraceID().String() + "-" + span.SpanContext().SpanID().String() + "-01"
+				So(traceParent, ShouldEqual, expected)
+				So(client.GetHeaders().Get("traceparent"), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When a call fails", func() {
+			server.Close()
+			_, err := client.GET(postsEndpoint, nil, nil)
+
+			Convey("Then the error is recorded on the span", func() {
+				So(err, ShouldNotBeNil)
+				spans := recorder.Ended()
+				So(spans, ShouldHaveLength, 1)
+				So(spans[0].Status().Code, ShouldEqual, codes.Error)
+				So(spans[0].Events(), ShouldHaveLength, 1)
+				So(spans[0].Events()[0].Name, ShouldEqual, "exception")
+			})
+		})
+	})
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const proxyAuthorizationHeader = "Proxy-Authorization"
+
+// WithProxy makes the client send its calls through the provided HTTP proxy.
+// Credentials in the proxy URL, if any, are sent to it. By default, the proxy
+// is taken from the environment, as net/http does.
+func (client *Client) WithProxy(proxyURL *url.URL) *Client {
+	clone := client.clone()
+	clone.transport().Proxy = http.ProxyURL(proxyURL)
+	return clone
+}
+
+// Connect establishes a tunnel to hostPort, like "example.com:443", through
+// the proxy of the client, issuing a CONNECT. The returned connection is
+// bound to hostPort, and the caller must close it. It fails with ErrNoProxy
+// when no proxy applies to hostPort.
+func (client *Client) Connect(hostPort string) (net.Conn, error) {
+	transport, ok := client.httpClient.Transport.(*http.Transport)
+	if !ok {
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+
+	proxyURL, err := client.tunnelProxy(transport, hostPort)
+	if err != nil {
+		return nil, err
+	}
+
+	dial := transport.DialContext
+	if dial == nil {
+		dial = new(net.Dialer).DialContext
+	}
+
+	conn, err := dial(client.ctx, "tcp", proxyURL.Host)
+	if err != nil {
+		return nil, fmt.Errorf("Can't reach proxy %v: %w", proxyURL.Host, err)
+	}
+
+	reader, err := client.connectThrough(conn, proxyURL, hostPort)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &tunnelConn{Conn: conn, reader: reader}, nil
+}
+
+// tunnelProxy returns the proxy the transport uses for hostPort. Only HTTP
+// proxies are supported.
+func (client *Client) tunnelProxy(transport *http.Transport, hostPort string) (*url.URL, error) {
+	if transport.Proxy == nil {
+		return nil, ErrNoProxy
+	}
+
+	proxyURL, err := transport.Proxy(&http.Request{URL: &url.URL{Scheme: httpsScheme, Host: hostPort}})
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL == nil {
+		return nil, ErrNoProxy
+	}
+	if proxyURL.Scheme != httpScheme {
+		return nil, fmt.Errorf("Can't tunnel through a %v proxy", proxyURL.Scheme)
+	}
+
+	return proxyURL, nil
+}
+
+// connectThrough sends the CONNECT to the proxy and reads its answer, within
+// the client timeout. It returns the reader holding any bytes the proxy sent
+// after its answer.
+func (client *Client) connectThrough(conn net.Conn, proxyURL *url.URL, hostPort string) (*bufio.Reader, error) {
+	if timeout := client.httpClient.Timeout; timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: hostPort},
+		Host:   hostPort,
+		Header: http.Header{},
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		request.Header.Set(proxyAuthorizationHeader, "Basic "+credentials)
+	}
+
+	err := request.Write(conn)
+	if err != nil {
+		return nil, fmt.Errorf("Can't send CONNECT to proxy %v: %w", proxyURL.Host, err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, request)
+	if err != nil {
+		return nil, fmt.Errorf("Can't read CONNECT answer from proxy %v: %w", proxyURL.Host, err)
+	}
+
+	// The body of an accepted CONNECT is the tunnel itself, so it is never
+	// drained: the connection is handed over, or closed on failure.
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Proxy %v refused the tunnel to %v: %v", proxyURL.Host, hostPort, resp.Status)
+	}
+
+	return reader, nil
+}
+
+// tunnelConn reads through the reader that consumed the CONNECT answer, so no
+// tunneled bytes are lost.
+type tunnelConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (conn *tunnelConn) Read(p []byte) (int, error) {
+	return conn.reader.Read(p)
+}
@@ -0,0 +1,98 @@
+package api_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+func TestConnect(t *testing.T) {
+	Convey("Given a service behind a proxy that tunnels CONNECT calls", t, func() {
+		service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("Desayuno con diamantes"))
+		}))
+		defer service.Close()
+
+		var authorization string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Proxy-Authorization")
+			if r.Method != http.MethodConnect || r.Host != service.Listener.Addr().String() {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			target, err := net.Dial("tcp", r.Host)
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			defer target.Close()
+
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			conn, buffered, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			go func() {
+				io.Copy(target, buffered)
+				target.Close()
+			}()
+			io.Copy(conn, target)
+		}))
+		defer proxy.Close()
+
+		proxyURL, _ := url.Parse(proxy.URL)
+		proxyURL.User = url.UserPassword("truman", "capote")
+		client := api.MakeNewClient().WithProxy(proxyURL)
+
+		Convey("When we open a tunnel to the service and call it through it", func() {
+			conn, err := client.Connect(service.Listener.Addr().String())
+			So(err, ShouldBeNil)
+			defer conn.Close()
+
+			request, _ := http.NewRequest(http.MethodGet, service.URL+postsEndpoint, nil)
+			So(request.Write(conn), ShouldBeNil)
+			resp, err := http.ReadResponse(bufio.NewReader(conn), request)
+			So(err, ShouldBeNil)
+			body, _ := io.ReadAll(resp.Body)
+
+			Convey("Then the call reaches the service with the proxy credentials", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(string(body), ShouldEqual, "Desayuno con diamantes")
+				So(authorization, ShouldEqual, "Basic dHJ1bWFuOmNhcG90ZQ==")
+			})
+		})
+
+		Convey("When we open a tunnel the proxy refuses", func() {
+			_, err := client.Connect("unknown.invalid:443")
+
+			Convey("Then it fails", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "403")
+			})
+		})
+	})
+
+	Convey("Given a client without a proxy", t, func() {
+		client := api.MakeNewClient().WithProxy(nil)
+
+		Convey("When we open a tunnel", func() {
+			_, err := client.Connect("example.com:443")
+
+			Convey("Then it fails as there is no proxy", func() {
+				So(err, ShouldEqual, api.ErrNoProxy)
+			})
+		})
+	})
+}
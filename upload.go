@@ -0,0 +1,144 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	contentRangeHeader = "Content-Range"
+	rangeHeader        = "Range"
+	rangeUnitPrefix    = "bytes="
+)
+
+// statusResumeIncomplete is the status a resumable upload endpoint answers
+// with while the upload is not complete.
+const statusResumeIncomplete = http.StatusPermanentRedirect
+
+// ResumableUpload PUTs size bytes of content to path in chunks of chunkSize
+// bytes, each one described by a Content-Range header. After every chunk the
+// server reports, through the Range header of a 308 response, the last byte it
+// has acknowledged, and the upload continues from there.
+// When a chunk fails with a transport error or a 5XX status, the upload asks
+// the server for its current offset, asking again if that fails too, and
+// resumes from the last acknowledged byte, up to maxRetries times in total. It fails with ErrUploadStalled when the server
+// does not acknowledge any new byte more than maxRetries times in a row. The
+// returned response is the final one of the upload, or the last failed one
+// when retries are exhausted.
+func (client *Client) ResumableUpload(
+	path string,
+	content io.ReadSeeker,
+	size, chunkSize int64,
+	maxRetries int,
+) (*http.Response, error) {
+
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("Invalid upload chunk size: %v", chunkSize)
+	}
+
+	var offset int64
+	retries, stalls := 0, 0
+	for {
+		resp, err := client.uploadChunk(path, content, offset, size, chunkSize)
+		for isFailedChunk(resp, err) {
+			if retries >= maxRetries {
+				return resp, err
+			}
+			retries++
+			closeBody(resp)
+
+			client.logger.Warnf("Upload to %s failed at byte %d, resuming (%d/%d)\n", path, offset, retries, maxRetries)
+			resp, err = client.uploadStatus(path, size)
+		}
+
+		if resp.StatusCode != statusResumeIncomplete {
+			return resp, nil
+		}
+
+		acknowledged, err := acknowledgedOffset(resp)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if acknowledged > offset {
+			stalls = 0
+		} else if stalls++; stalls > maxRetries {
+			return nil, fmt.Errorf("%w at byte %d", ErrUploadStalled, acknowledged)
+		}
+		offset = acknowledged
+	}
+}
+
+func (client *Client) uploadChunk(
+	path string,
+	content io.ReadSeeker,
+	offset, size, chunkSize int64,
+) (*http.Response, error) {
+
+	if offset >= size {
+		return client.uploadStatus(path, size)
+	}
+
+	_, err := content.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	length := size - offset
+	if length > chunkSize {
+		length = chunkSize
+	}
+
+	chunk := make([]byte, length)
+	_, err = io.ReadFull(content, chunk)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := client.snapshotHeaders().Clone()
+	headers.Set(contentRangeHeader, fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, size))
+	return client.executeCallWithHeaders(http.MethodPut, path, bytes.NewReader(chunk), nil, headers)
+}
+
+// uploadStatus asks the server for the state of the upload with an empty PUT.
+func (client *Client) uploadStatus(path string, size int64) (*http.Response, error) {
+	headers := client.snapshotHeaders().Clone()
+	headers.Set(contentRangeHeader, fmt.Sprintf("bytes */%d", size))
+	return client.executeCallWithHeaders(http.MethodPut, path, http.NoBody, nil, headers)
+}
+
+func isFailedChunk(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// acknowledgedOffset returns the next byte to upload from a Range header in
+// the form "bytes=0-N". A missing header means that nothing was received yet.
+func acknowledgedOffset(resp *http.Response) (int64, error) {
+	acknowledged := resp.Header.Get(rangeHeader)
+	if acknowledged == "" {
+		return 0, nil
+	}
+
+	bounds := strings.SplitN(strings.TrimPrefix(acknowledged, rangeUnitPrefix), "-", 2)
+	if len(bounds) != 2 {
+		return 0, fmt.Errorf("Invalid Range header in upload response: %v", acknowledged)
+	}
+
+	last, err := strconv.ParseInt(bounds[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid Range header in upload response: %v", acknowledged)
+	}
+
+	return last + 1, nil
+}
+
+func closeBody(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,158 @@
+package api_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+const uploadEndpoint = "/uploads/1"
+
+// resumableServer acknowledges uploaded chunks through the Range header. The
+// first time it receives the chunk starting at failAt it only keeps half of it
+// and fails, simulating a connection dropped in the middle of the upload. The
+// first failStatus status queries fail too.
+type resumableServer struct {
+	sync.Mutex
+	received      []byte
+	failAt        int
+	failed        bool
+	failStatus    int
+	statusQueries int
+}
+
+func (s *resumableServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	contentRange := strings.TrimPrefix(r.Header.Get("Content-Range"), "bytes ")
+	parts := strings.Split(contentRange, "/")
+	size, _ := strconv.Atoi(parts[1])
+	chunk, _ := ioutil.ReadAll(r.Body)
+
+	if parts[0] == "*" {
+		s.statusQueries++
+		if s.statusQueries <= s.failStatus {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	} else {
+		start, _ := strconv.Atoi(strings.Split(parts[0], "-")[0])
+		if start != len(s.received) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if start == s.failAt && !s.failed {
+			s.failed = true
+			s.received = append(s.received, chunk[:len(chunk)/2]...)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		s.received = append(s.received, chunk...)
+	}
+
+	if len(s.received) == size {
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	if len(s.received) > 0 {
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(s.received)-1))
+	}
+	w.WriteHeader(http.StatusPermanentRedirect)
+}
+
+func TestResumableUpload(t *testing.T) {
+	Convey("Given a resumable upload endpoint that fails in the middle of the upload", t, func() {
+		uploadServer := &resumableServer{failAt: 10}
+		server := httptest.NewServer(uploadServer)
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+		content := []byte("Desayuno con diamantes, Truman Capote")
+
+		Convey("When we upload the content in chunks", func() {
+			resp, err := client.ResumableUpload(uploadEndpoint, bytes.NewReader(content), int64(len(content)), 10, 1)
+
+			Convey("Then the upload resumes from the last acknowledged byte", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusCreated)
+				So(uploadServer.statusQueries, ShouldEqual, 1)
+				So(string(uploadServer.received), ShouldEqual, string(content))
+			})
+		})
+
+		Convey("When we upload the content without retries", func() {
+			resp, err := client.ResumableUpload(uploadEndpoint, bytes.NewReader(content), int64(len(content)), 10, 0)
+
+			Convey("Then we obtain the failed response", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+			})
+		})
+	})
+}
+
+func TestResumableUploadFailedStatus(t *testing.T) {
+	Convey("Given a resumable upload endpoint whose first status query fails too", t, func() {
+		uploadServer := &resumableServer{failAt: 10, failStatus: 1}
+		server := httptest.NewServer(uploadServer)
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+		content := []byte("Desayuno con diamantes, Truman Capote")
+
+		Convey("When we upload the content in chunks", func() {
+			resp, err := client.ResumableUpload(uploadEndpoint, bytes.NewReader(content), int64(len(content)), 10, 2)
+
+			Convey("Then the status is asked again and the upload resumes", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusCreated)
+				So(uploadServer.statusQueries, ShouldEqual, 2)
+				So(string(uploadServer.received), ShouldEqual, string(content))
+			})
+		})
+
+		Convey("When the retries run out on the failed status query", func() {
+			resp, err := client.ResumableUpload(uploadEndpoint, bytes.NewReader(content), int64(len(content)), 10, 1)
+
+			Convey("Then we obtain the failed status response", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+				So(uploadServer.statusQueries, ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func TestResumableUploadStalled(t *testing.T) {
+	Convey("Given a resumable upload endpoint that never acknowledges past the fifth byte", t, func() {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Range", "bytes=0-4")
+			w.WriteHeader(http.StatusPermanentRedirect)
+		}))
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+		content := []byte("Desayuno con diamantes, Truman Capote")
+
+		Convey("When we upload the content in chunks", func() {
+			resp, err := client.ResumableUpload(uploadEndpoint, bytes.NewReader(content), int64(len(content)), 10, 2)
+
+			Convey("Then it gives up once the retries are exhausted", func() {
+				So(resp, ShouldBeNil)
+				So(errors.Is(err, api.ErrUploadStalled), ShouldBeTrue)
+				So(calls, ShouldEqual, 4)
+			})
+		})
+	})
+}
@@ -1,7 +1,10 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -33,6 +36,41 @@ func IsErrorResponse(err error) bool {
 	return ok
 }
 
+// Sentinel errors matching the ErrorResponse with the corresponding Code, so
+// callers can write errors.Is(err, ErrNotFound). Use errors.As to extract the
+// *ErrorResponse itself.
+var (
+	ErrBadRequest          = errors.New("bad request")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrForbidden           = errors.New("forbidden")
+	ErrNotFound            = errors.New("not found")
+	ErrConflict            = errors.New("conflict")
+	ErrUnprocessableEntity = errors.New("unprocessable entity")
+	ErrTooManyRequests     = errors.New("too many requests")
+	ErrInternalServerError = errors.New("internal server error")
+	ErrBadGateway          = errors.New("bad gateway")
+	ErrServiceUnavailable  = errors.New("service unavailable")
+)
+
+var statusErrors = map[int]error{
+	http.StatusBadRequest:          ErrBadRequest,
+	http.StatusUnauthorized:        ErrUnauthorized,
+	http.StatusForbidden:           ErrForbidden,
+	http.StatusNotFound:            ErrNotFound,
+	http.StatusConflict:            ErrConflict,
+	http.StatusUnprocessableEntity: ErrUnprocessableEntity,
+	http.StatusTooManyRequests:     ErrTooManyRequests,
+	http.StatusInternalServerError: ErrInternalServerError,
+	http.StatusBadGateway:          ErrBadGateway,
+	http.StatusServiceUnavailable:  ErrServiceUnavailable,
+}
+
+// Is reports whether target is the sentinel error of the response Code.
+func (e *ErrorResponse) Is(target error) bool {
+	sentinel, ok := statusErrors[e.Code]
+	return ok && sentinel == target
+}
+
 // PaginatedResponse models a paginate response from services.
 type PaginatedResponse struct {
 	Total int           `json:"total,omitempty"`
@@ -59,23 +97,192 @@ func GetNewGinContextWithAuthBearer() (*gin.Context, string) {
 	return ctx, testAuthBearer
 }
 
+// RespondError forwards err to the gin client. If err is, or wraps, an
+// *ErrorResponse, its Code and JSON are written to the context. Any other error
+// is written as a 500 general error. A nil err is written as a bare 500, with
+// just its status text.
+func RespondError(c *gin.Context, err error) {
+	if err == nil {
+		c.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	errorResponse := new(ErrorResponse)
+	if !errors.As(err, &errorResponse) {
+		errorResponse = &ErrorResponse{
+			Name:      "GeneralError",
+			Message:   err.Error(),
+			Code:      http.StatusInternalServerError,
+			ClassName: "general-error",
+		}
+	}
+
+	code := errorResponse.Code
+	if code == 0 {
+		code = http.StatusInternalServerError
+	}
+
+	c.JSON(code, errorResponse)
+}
+
+// IsPaginated reports whether the response body is a paginated envelope, an
+// object with data and any of total, limit and skip. The body is restored, so
+// it can be parsed afterwards.
+func IsPaginated(resp *http.Response) (bool, error) {
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return false, nil
+	}
+
+	var envelope map[string]json.RawMessage
+	err = json.Unmarshal(trimmed, &envelope)
+	if err != nil {
+		return false, err
+	}
+
+	_, hasData := envelope["data"]
+	_, hasTotal := envelope["total"]
+	_, hasLimit := envelope["limit"]
+	_, hasSkip := envelope["skip"]
+	return hasData && (hasTotal || hasLimit || hasSkip), nil
+}
+
 // ParseAllPaginated parses all occurrences of a paginated response to the
 // receiver.
 func ParseAllPaginated(resp *http.Response, receiver interface{}) error {
+	if !usesCustomDecoding(resp) {
+		return parseRawPaginatedData(resp, receiver)
+	}
+
 	paginatedData, err := getPaginatedData(resp)
 	if err != nil {
 		return err
 	}
 
-	return ParseTo(paginatedData.Data, receiver)
+	return parseResponseData(resp, paginatedData.Data, receiver)
+}
+
+// PaginatedResponseT models a paginate response from services with typed data.
+type PaginatedResponseT[T any] struct {
+	Total int `json:"total,omitempty"`
+	Limit int `json:"limit,omitempty"`
+	Skip  int `json:"skip,omitempty"`
+	Data  []T `json:"data,omitempty"`
+}
+
+// ParseAllPaginatedT returns all occurrences of a paginated response, decoding
+// the data array straight into a []T.
+func ParseAllPaginatedT[T any](resp *http.Response) ([]T, error) {
+	if usesCustomDecoding(resp) {
+		var data []T
+		err := ParseAllPaginated(resp, &data)
+		return data, err
+	}
+
+	paginatedData := new(PaginatedResponseT[T])
+	err := ParseResponse(resp, paginatedData)
+	if err != nil {
+		return nil, err
+	}
+
+	return paginatedData.Data, nil
+}
+
+// rawPaginatedResponse keeps the data of a paginated response undecoded, so it
+// can be decoded straight into the receiver.
+type rawPaginatedResponse struct {
+	Total int             `json:"total,omitempty"`
+	Limit int             `json:"limit,omitempty"`
+	Skip  int             `json:"skip,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// dataInto decodes the data into the receiver slice. As some services return
+// a lone object instead of an array when there is a single result, an object
+// is decoded as an array of length one.
+func (paginatedData *rawPaginatedResponse) dataInto(receiver interface{}) error {
+	data := bytes.TrimSpace(paginatedData.Data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	if data[0] == '{' {
+		data = append(append([]byte{'['}, data...), ']')
+	}
+
+	return json.Unmarshal(data, receiver)
+}
+
+// UnmarshalJSON decodes a paginated response, accepting its data as either an
+// array or a lone object.
+func (response *PaginatedResponse) UnmarshalJSON(data []byte) error {
+	paginatedData := new(rawPaginatedResponse)
+	err := json.Unmarshal(data, paginatedData)
+	if err != nil {
+		return err
+	}
+
+	response.Total = paginatedData.Total
+	response.Limit = paginatedData.Limit
+	response.Skip = paginatedData.Skip
+	return paginatedData.dataInto(&response.Data)
+}
+
+// UnmarshalJSON decodes a paginated response, accepting its data as either an
+// array or a lone object.
+func (response *PaginatedResponseT[T]) UnmarshalJSON(data []byte) error {
+	paginatedData := new(rawPaginatedResponse)
+	err := json.Unmarshal(data, paginatedData)
+	if err != nil {
+		return err
+	}
+
+	response.Total = paginatedData.Total
+	response.Limit = paginatedData.Limit
+	response.Skip = paginatedData.Skip
+	return paginatedData.dataInto(&response.Data)
+}
+
+func parseRawPaginatedData(resp *http.Response, receiver interface{}) error {
+	if !isAPointer(receiver) {
+		return NewNotAPointerError()
+	}
+
+	paginatedData := new(rawPaginatedResponse)
+	err := ParseResponse(resp, paginatedData)
+	if err != nil {
+		return err
+	}
+
+	err = paginatedData.dataInto(receiver)
+	if err != nil {
+		return fmt.Errorf("Error: %v\nCan't unmarshal response data: %s", err, paginatedData.Data)
+	}
+
+	return nil
 }
 
 func getPaginatedData(resp *http.Response) (*PaginatedResponse, error) {
-	if !isValidResponse(resp) {
+	if !IsSuccess(resp) {
 		return nil, parseError(resp)
 	}
 
 	paginatedData := new(PaginatedResponse)
+	if options := responseDecoding(resp); options != nil && len(options.decoders) > 0 {
+		err := decodeWithChain(resp, options.decoders, paginatedData)
+		if err != nil {
+			return nil, err
+		}
+		return paginatedData, nil
+	}
+
 	body, err := Body2Interface(resp)
 	if err != nil {
 		return nil, err
@@ -101,6 +308,10 @@ func parseError(resp *http.Response) error {
 		return inferError(resp)
 	}
 
+	if errorResponse.Code == 0 {
+		errorResponse.Code = resp.StatusCode
+	}
+
 	return errorResponse
 }
 
@@ -140,22 +351,89 @@ func ParseOnePaginated(resp *http.Response, receiver interface{}) error {
 		return new(NoDataFetched)
 	}
 
-	return ParseTo(paginatedData.Data[0], receiver)
+	return parseResponseData(resp, paginatedData.Data[0], receiver)
 }
 
 // ParseResponseTo parses the response body to the receiver.
 func ParseResponseTo(resp *http.Response, receiver interface{}) error {
-	if !isValidResponse(resp) {
+	if !IsSuccess(resp) {
 		return parseError(resp)
 	}
 
+	if options := responseDecoding(resp); options != nil {
+		if len(options.decoders) > 0 {
+			return decodeWithChain(resp, options.decoders, receiver)
+		}
+		if options.codec != nil && !options.looseNumbers {
+			return decodeWithCodec(resp, options.codec, receiver)
+		}
+	}
+
 	body, err := Body2Interface(resp)
 	if err != nil {
 		return err
 	}
 
-	return ParseTo(body, receiver)
+	return parseResponseData(resp, body, receiver)
+
+}
+
+// ParseXMLTo decodes the XML response body into the receiver.
+func ParseXMLTo(resp *http.Response, receiver interface{}) error {
+	if !IsSuccess(resp) {
+		return parseError(resp)
+	}
+
+	if !isAPointer(receiver) {
+		return NewNotAPointerError()
+	}
+
+	err := xml.NewDecoder(resp.Body).Decode(receiver)
+	if err != nil {
+		return fmt.Errorf("Error: %v\nCan't decode XML response body", err)
+	}
+
+	return nil
+}
+
+// ParseResponse decodes the response body straight into the receiver. Unlike
+// ParseResponseTo, it skips the intermediate interface{}, so it is faster and
+// keeps the precision of large integers. Responses of clients with a decoder
+// chain or loose numbers are parsed as ParseResponseTo does.
+func ParseResponse(resp *http.Response, receiver interface{}) error {
+	if usesCustomDecoding(resp) {
+		return ParseResponseTo(resp, receiver)
+	}
+
+	if !IsSuccess(resp) {
+		return parseError(resp)
+	}
+
+	if !isAPointer(receiver) {
+		return NewNotAPointerError()
+	}
+
+	err := json.NewDecoder(resp.Body).Decode(receiver)
+	if err != nil {
+		return fmt.Errorf("Error: %v\nCan't decode response body", err)
+	}
+
+	return nil
+}
 
+func usesCustomDecoding(resp *http.Response) bool {
+	options := responseDecoding(resp)
+	return options != nil && !options.isDefault()
+}
+
+// parseResponseData parses data decoded from resp into the receiver, honoring
+// the decoding options of the client that made the call.
+func parseResponseData(resp *http.Response, data, receiver interface{}) error {
+	if options := responseDecoding(resp); options != nil && options.looseNumbers {
+		data = coerceNumbers(data, reflect.TypeOf(receiver))
+	}
+
+	return ParseTo(data, receiver)
 }
 
 // ParseTo parses generic interface to another. As this is a generic function
@@ -185,9 +463,30 @@ func Body2Interface(resp *http.Response) (interface{}, error) {
 		return nil, err
 	}
 
+	unmarshal := json.Unmarshal
+	if options := responseDecoding(resp); options != nil && options.codec != nil {
+		unmarshal = options.codec.Unmarshal
+	}
+
 	var data interface{}
 
-	err = json.Unmarshal(body, &data)
+	err = unmarshal(body, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Body2InterfaceUseNumber parses a body of an http response to a empty
+// interface as Body2Interface does, but keeping numbers as json.Number instead
+// of float64, so large integers, like 64-bit IDs, survive a ParseTo round trip.
+func Body2InterfaceUseNumber(resp *http.Response) (interface{}, error) {
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+
+	var data interface{}
+	err := decoder.Decode(&data)
 	if err != nil {
 		return nil, err
 	}
@@ -217,13 +516,21 @@ func IsNotAPointerError(err error) bool {
 	return ok
 }
 
-func isValidResponse(response *http.Response) bool {
+// IsSuccess returns if the response status is a 2XX or 3XX code.
+func IsSuccess(response *http.Response) bool {
 	return response.StatusCode >= http.StatusOK && response.StatusCode < http.StatusBadRequest
 }
 
-// IsValidResponse returns if the response status is a 2XX or 3XX code.
+// IsValidResponse returns if the response status is a 2XX or 3XX code. It is
+// the same as IsSuccess.
 func IsValidResponse(response *http.Response) bool {
-	return isValidResponse(response)
+	return IsSuccess(response)
+}
+
+// StatusClass returns the class of the response status, that is, its first
+// digit: 2 for a 2XX code, 4 for a 4XX code, and so on.
+func StatusClass(response *http.Response) int {
+	return response.StatusCode / 100
 }
 
 // NoDataFetched is used when response is valid, bad data is empty
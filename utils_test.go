@@ -0,0 +1,287 @@
+package api_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+const largeID = int64(9007199254740993)
+
+type identifiedPost struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+func TestParseResponse(t *testing.T) {
+	Convey("Given a response with an id beyond float64 precision", t, func() {
+		body := fmt.Sprintf(`{"id":%d,"title":"Desayuno con diamantes"}`, largeID)
+
+		Convey("When we parse it with ParseResponse", func() {
+			post := new(identifiedPost)
+			err := api.ParseResponse(newJSONResponse(body), post)
+
+			Convey("Then the int64 precision is preserved", func() {
+				So(err, ShouldBeNil)
+				So(post.ID, ShouldEqual, largeID)
+				So(post.Title, ShouldEqual, "Desayuno con diamantes")
+			})
+		})
+
+		Convey("When we parse it to a non pointer", func() {
+			err := api.ParseResponse(newJSONResponse(body), identifiedPost{})
+
+			Convey("Then it fails", func() {
+				So(api.IsNotAPointerError(err), ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given a paginated response with an id beyond float64 precision", t, func() {
+		body := fmt.Sprintf(`{"total":1,"limit":10,"skip":0,"data":[{"id":%d}]}`, largeID)
+
+		Convey("When we parse all its data", func() {
+			var posts []identifiedPost
+			err := api.ParseAllPaginated(newJSONResponse(body), &posts)
+
+			Convey("Then the int64 precision is preserved", func() {
+				So(err, ShouldBeNil)
+				So(posts, ShouldHaveLength, 1)
+				So(posts[0].ID, ShouldEqual, largeID)
+			})
+		})
+	})
+}
+
+func TestParseAllPaginatedT(t *testing.T) {
+	Convey("Given a paginated response", t, func() {
+		body := fmt.Sprintf(`{"total":2,"limit":10,"skip":0,"data":[{"id":%d,"title":"Desayuno con diamantes"},{"id":2}]}`, largeID)
+
+		Convey("When we parse all its data into a typed slice", func() {
+			posts, err := api.ParseAllPaginatedT[identifiedPost](newJSONResponse(body))
+
+			Convey("Then every item is decoded", func() {
+				So(err, ShouldBeNil)
+				So(posts, ShouldResemble, []identifiedPost{
+					{ID: largeID, Title: "Desayuno con diamantes"},
+					{ID: 2},
+				})
+			})
+		})
+	})
+
+	Convey("Given a client with loose numbers", t, func() {
+		server := newStaticServer("application/json", `{"total":1,"data":[{"id":"7","title":"Desayuno con diamantes"}]}`)
+		defer server.Close()
+		resp, err := api.MakeNewClient().WithBasePath(server.URL).WithLooseNumbers().GET(postsEndpoint, nil)
+		So(err, ShouldBeNil)
+
+		Convey("When we parse all its data into a typed slice", func() {
+			posts, err := api.ParseAllPaginatedT[identifiedPost](resp)
+
+			Convey("Then the client options are honored", func() {
+				So(err, ShouldBeNil)
+				So(posts, ShouldHaveLength, 1)
+				So(posts[0].ID, ShouldEqual, 7)
+			})
+		})
+	})
+}
+
+func TestPaginatedDataShapes(t *testing.T) {
+	shapes := map[string]string{
+		"an array":      `{"total":1,"data":[{"id":1,"title":"Desayuno con diamantes"}]}`,
+		"a lone object": `{"total":1,"data":{"id":1,"title":"Desayuno con diamantes"}}`,
+	}
+	expected := identifiedPost{ID: 1, Title: "Desayuno con diamantes"}
+
+	for shape, body := range shapes {
+		Convey(fmt.Sprintf("Given a paginated response with data as %v", shape), t, func() {
+
+			Convey("When we parse all its data", func() {
+				var posts []identifiedPost
+				err := api.ParseAllPaginated(newJSONResponse(body), &posts)
+
+				Convey("Then it is a slice with the item", func() {
+					So(err, ShouldBeNil)
+					So(posts, ShouldResemble, []identifiedPost{expected})
+				})
+			})
+
+			Convey("When we parse its first item", func() {
+				post := new(identifiedPost)
+				err := api.ParseOnePaginated(newJSONResponse(body), post)
+
+				Convey("Then it is the item", func() {
+					So(err, ShouldBeNil)
+					So(*post, ShouldResemble, expected)
+				})
+			})
+
+			Convey("When we parse all its data into a typed slice", func() {
+				posts, err := api.ParseAllPaginatedT[identifiedPost](newJSONResponse(body))
+
+				Convey("Then it is a slice with the item", func() {
+					So(err, ShouldBeNil)
+					So(posts, ShouldResemble, []identifiedPost{expected})
+				})
+			})
+
+			Convey("When we parse it to a PaginatedResponse", func() {
+				page := new(api.PaginatedResponse)
+				err := api.ParseResponseTo(newJSONResponse(body), page)
+
+				Convey("Then its data has length one", func() {
+					So(err, ShouldBeNil)
+					So(page.Total, ShouldEqual, 1)
+					So(page.Data, ShouldHaveLength, 1)
+				})
+			})
+		})
+	}
+}
+
+func TestIsPaginated(t *testing.T) {
+	Convey("Given an enveloped response", t, func() {
+		resp := newJSONResponse(`{"total":1,"limit":10,"skip":0,"data":[{"id":1}]}`)
+
+		Convey("When we check whether it is paginated", func() {
+			paginated, err := api.IsPaginated(resp)
+
+			Convey("Then it is, and the body can still be parsed", func() {
+				So(err, ShouldBeNil)
+				So(paginated, ShouldBeTrue)
+				var posts []identifiedPost
+				So(api.ParseAllPaginated(resp, &posts), ShouldBeNil)
+				So(posts, ShouldResemble, []identifiedPost{{ID: 1}})
+			})
+		})
+	})
+
+	Convey("Given bare responses", t, func() {
+		bodies := []string{`[{"id":1}]`, `{"id":1,"data":"none"}`, `{"total":1}`}
+
+		Convey("When we check whether they are paginated", func() {
+			Convey("Then they are not", func() {
+				for _, body := range bodies {
+					paginated, err := api.IsPaginated(newJSONResponse(body))
+					So(err, ShouldBeNil)
+					So(paginated, ShouldBeFalse)
+				}
+			})
+		})
+	})
+
+	Convey("Given a malformed response", t, func() {
+		resp := newJSONResponse(`{"data":`)
+
+		Convey("When we check whether it is paginated", func() {
+			_, err := api.IsPaginated(resp)
+
+			Convey("Then it fails", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestBody2InterfaceUseNumber(t *testing.T) {
+	Convey("Given a response with an id beyond float64 precision", t, func() {
+		body := fmt.Sprintf(`{"id":%d,"title":"Desayuno con diamantes"}`, largeID)
+
+		Convey("When we round trip it through Body2InterfaceUseNumber and ParseTo", func() {
+			data, err := api.Body2InterfaceUseNumber(newJSONResponse(body))
+			So(err, ShouldBeNil)
+			post := new(identifiedPost)
+			err = api.ParseTo(data, post)
+
+			Convey("Then the id survives", func() {
+				So(err, ShouldBeNil)
+				So(post.ID, ShouldEqual, largeID)
+			})
+		})
+
+		Convey("When we round trip it through Body2Interface and ParseTo", func() {
+			data, err := api.Body2Interface(newJSONResponse(body))
+			So(err, ShouldBeNil)
+			post := new(identifiedPost)
+			err = api.ParseTo(data, post)
+
+			Convey("Then the id loses precision", func() {
+				So(err, ShouldBeNil)
+				So(post.ID, ShouldNotEqual, largeID)
+			})
+		})
+	})
+}
+
+func benchmarkPostsBody() string {
+	posts := make([]string, 100)
+	for i := range posts {
+		posts[i] = fmt.Sprintf(`{"id":%d,"title":"Desayuno con diamantes"}`, i)
+	}
+
+	return fmt.Sprintf(`{"total":100,"limit":100,"skip":0,"data":[%v]}`, strings.Join(posts, ","))
+}
+
+func BenchmarkParseResponse(b *testing.B) {
+	body := benchmarkPostsBody()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var posts []identifiedPost
+		if err := api.ParseAllPaginated(newJSONResponse(body), &posts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseAllPaginatedT(b *testing.B) {
+	body := benchmarkPostsBody()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := api.ParseAllPaginatedT[identifiedPost](newJSONResponse(body)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseResponseTo(b *testing.B) {
+	body := benchmarkPostsBody()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		page := new(api.PaginatedResponse)
+		if err := api.ParseResponseTo(newJSONResponse(body), page); err != nil {
+			b.Fatal(err)
+		}
+		var posts []identifiedPost
+		if err := api.ParseTo(page.Data, &posts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	Convey("Given responses of every status class", t, func() {
+		statuses := []int{http.StatusContinue, http.StatusOK, http.StatusFound, http.StatusNotFound, http.StatusBadGateway}
+
+		Convey("When we classify them", func() {
+			classes := []int{}
+			successes := []bool{}
+			for _, status := range statuses {
+				resp := &http.Response{StatusCode: status}
+				classes = append(classes, api.StatusClass(resp))
+				successes = append(successes, api.IsSuccess(resp))
+			}
+
+			Convey("Then we obtain the first digit of the status and only 2XX and 3XX succeed", func() {
+				So(classes, ShouldResemble, []int{1, 2, 3, 4, 5})
+				So(successes, ShouldResemble, []bool{false, true, true, false, false})
+			})
+		})
+	})
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// UpdateVersioned performs a read-modify-write of the resource at path for
+// services that embed a version in the resource and expect it echoed back.
+// It GETs the resource into resource, calls mutate to modify it and PUTs it
+// back with the original value of versionField, so a concurrent update is
+// detected by the service. The PUT response is parsed into resource.
+// If the service answers with a 409 status, a *ConflictError is returned.
+func (client *Client) UpdateVersioned(path, versionField string, resource interface{}, mutate func() error) error {
+	version, err := client.getVersioned(path, versionField, resource)
+	if err != nil {
+		return err
+	}
+
+	err = mutate()
+	if err != nil {
+		return err
+	}
+
+	body := map[string]interface{}{}
+	err = ParseTo(resource, &body)
+	if err != nil {
+		return err
+	}
+	body[versionField] = version
+
+	resp, err := client.PUT(path, body, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict {
+		return &ConflictError{Version: version, Err: parseError(resp)}
+	}
+
+	return ParseResponseTo(resp, resource)
+}
+
+// getVersioned reads the current version of the resource, always from the
+// service, as a cached one would make the update fail.
+func (client *Client) getVersioned(path, versionField string, resource interface{}) (interface{}, error) {
+	resp, err := client.WithCacheMethods().GET(path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if !IsSuccess(resp) {
+		return nil, parseError(resp)
+	}
+
+	current := map[string]interface{}{}
+	err = ParseResponseTo(resp, &current)
+	if err != nil {
+		return nil, err
+	}
+
+	version, ok := current[versionField]
+	if !ok {
+		return nil, fmt.Errorf("Resource at %v has no version field %v", path, versionField)
+	}
+
+	return version, ParseTo(current, resource)
+}
+
+// ConflictError is used when a versioned update is rejected because the
+// resource was modified since it was read.
+type ConflictError struct {
+	Version interface{}
+	Err     error
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("Conflict updating resource version %v: %v", e.Version, e.Err)
+}
+
+// Unwrap returns the error response sent by the service.
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// IsConflictError checks if the error is a ConflictError error.
+func IsConflictError(err error) bool {
+	_, ok := err.(*ConflictError)
+	return ok
+}
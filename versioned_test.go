@@ -0,0 +1,117 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "github.com/orov-io/BlackBeard"
+)
+
+type versionedPost struct {
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	Version int    `json:"version"`
+}
+
+// versionedServer holds a single post and only accepts updates that echo its
+// current version, bumping it on every update.
+type versionedServer struct {
+	sync.Mutex
+	post versionedPost
+}
+
+func (s *versionedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	if r.Method == http.MethodPut {
+		update := versionedPost{}
+		json.NewDecoder(r.Body).Decode(&update)
+		if update.Version != s.post.Version {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(api.ErrorResponse{Name: "Conflict", Code: http.StatusConflict})
+			return
+		}
+		s.post.Title = update.Title
+		s.post.Version++
+	}
+
+	json.NewEncoder(w).Encode(s.post)
+}
+
+func (s *versionedServer) bumpVersion() {
+	s.Lock()
+	defer s.Unlock()
+	s.post.Version++
+}
+
+func TestUpdateVersioned(t *testing.T) {
+	Convey("Given a service with a versioned resource", t, func() {
+		versioned := &versionedServer{post: versionedPost{ID: 1, Title: "json-server", Version: 3}}
+		server := httptest.NewServer(versioned)
+		defer server.Close()
+		client := api.MakeNewClient().WithBasePath(server.URL)
+		post := new(versionedPost)
+
+		Convey("When we update the resource", func() {
+			err := client.UpdateVersioned(postsEndpoint+"/1", "version", post, func() error {
+				post.Title = "Desayuno con diamantes"
+				post.Version = 0
+				return nil
+			})
+
+			Convey("Then the update succeeds preserving the read version", func() {
+				So(err, ShouldBeNil)
+				So(post.Title, ShouldEqual, "Desayuno con diamantes")
+				So(post.Version, ShouldEqual, 4)
+				So(versioned.post.Title, ShouldEqual, "Desayuno con diamantes")
+			})
+		})
+
+		Convey("When the resource is modified while we update it", func() {
+			err := client.UpdateVersioned(postsEndpoint+"/1", "version", post, func() error {
+				post.Title = "Desayuno con diamantes"
+				versioned.bumpVersion()
+				return nil
+			})
+
+			Convey("Then we obtain a conflict error", func() {
+				So(api.IsConflictError(err), ShouldBeTrue)
+				So(versioned.post.Title, ShouldEqual, "json-server")
+			})
+		})
+
+		Convey("When we update the resource with a stale version in the cache", func() {
+			cached := client.WithCache()
+			_, err := cached.GET(postsEndpoint+"/1", nil)
+			So(err, ShouldBeNil)
+			versioned.bumpVersion()
+			err = cached.UpdateVersioned(postsEndpoint+"/1", "version", post, func() error {
+				post.Title = "Desayuno con diamantes"
+				return nil
+			})
+
+			Convey("Then the current version is read from the service", func() {
+				So(err, ShouldBeNil)
+				So(post.Version, ShouldEqual, 5)
+			})
+		})
+
+		Convey("When the resource does not exist", func() {
+			missing := httptest.NewServer(http.NotFoundHandler())
+			defer missing.Close()
+			err := api.MakeNewClient().WithBasePath(missing.URL).UpdateVersioned(postsEndpoint+"/1", "version", post, func() error {
+				return nil
+			})
+
+			Convey("Then we obtain the error response", func() {
+				So(api.IsErrorResponse(err), ShouldBeTrue)
+			})
+		})
+	})
+}